@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gecosys/gsc-go/internal/randid"
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+// ErrAckTimeout is returned when the delivery is not confirmed after all attempts
+var ErrAckTimeout = errors.New("Delivery is not confirmed")
+
+// AckMode is who confirms the delivery of a message
+type AckMode int
+
+const (
+	// AckFromHub waits for GSCHub receiving the message
+	AckFromHub AckMode = iota
+	// AckFromReceiver waits for the receiver receiving the message
+	AckFromReceiver
+)
+
+// AckOptions configures SendMessageWithAck
+type AckOptions struct {
+	// Mode is who confirms the delivery (default: AckFromHub)
+	Mode AckMode
+	// Timeout is the limit of time to wait for every attempt (default: requestTimeout)
+	Timeout time.Duration
+	// Retries is the number of resending when the delivery is not confirmed in time,
+	// negative values are the same as 0 (default: 0).
+	// The resent messages have the same id, so the receiver can drop duplicates.
+	Retries int
+}
+
+// SendMessageWithAck sends message and waits for confirmation of the delivery.
+// It returns id of the message, the id is GEHMessage.ID on the receiver's side.
+func (c *client) SendMessageWithAck(ctx context.Context, receiver string, data []byte, isEncrypted bool, opts *AckOptions) (string, error) {
+	var (
+		err     error
+		id      string
+		timeout = requestTimeout
+		mode    = pb.Letter_AckHub
+		retries = 0
+	)
+	if opts != nil {
+		if opts.Timeout > 0 {
+			timeout = opts.Timeout
+		}
+		if opts.Mode == AckFromReceiver {
+			mode = pb.Letter_AckReceiver
+		}
+		if opts.Retries > 0 {
+			retries = opts.Retries
+		}
+	}
+
+	id, err = randid.New()
+	if err != nil {
+		return "", err
+	}
+	chanAck := c.waitAck(id)
+	defer c.cancelAck(id)
+
+	letter := &pb.Letter{
+		Type:     pb.Letter_Single,
+		Receiver: receiver,
+		Data:     data,
+		ID:       id,
+		Ack:      mode,
+	}
+	defer c.cancelWritten(id)
+
+	// chanWritten is not nil while the letter waits in the outbox,
+	// the letter is not queued again and its attempt starts when it is written
+	var (
+		isQueued    bool
+		chanWritten chan struct{}
+	)
+	for attempt := 0; attempt <= retries; attempt++ {
+		if chanWritten == nil {
+			chanWritten = c.waitWritten(id)
+			isQueued, err = c.sendOrQueueLetter(ctx, letter, isEncrypted)
+			if err != nil {
+				return id, err
+			}
+			if isQueued == false {
+				c.cancelWritten(id)
+				chanWritten = nil
+			}
+		}
+
+		err = c.waitAckAttempt(ctx, chanAck, &chanWritten, timeout)
+		if err != ErrAckTimeout {
+			return id, err
+		}
+	}
+	return id, ErrAckTimeout
+}
+
+// waitAckAttempt waits for the acknowledgement at most timeout after the letter is written,
+// it returns ErrAckTimeout if the attempt is over. *chanWritten is set to nil when it is closed.
+func (c *client) waitAckAttempt(ctx context.Context, chanAck chan struct{}, chanWritten *chan struct{}, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-chanAck:
+			return nil
+		case <-*chanWritten:
+			// The letter left the outbox just now, so the attempt starts again
+			*chanWritten = nil
+			if timer.Stop() == false {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return ErrClosed
+		case <-timer.C:
+			return ErrAckTimeout
+		}
+	}
+}
+
+// waitAck registers the message, the returned channel is closed when its delivery is confirmed
+func (c *client) waitAck(id string) chan struct{} {
+	c.mtxAcks.Lock()
+	defer c.mtxAcks.Unlock()
+	chanAck := make(chan struct{})
+	c.acks[id] = chanAck
+	return chanAck
+}
+
+func (c *client) cancelAck(id string) {
+	c.mtxAcks.Lock()
+	defer c.mtxAcks.Unlock()
+	delete(c.acks, id)
+}
+
+// resolveAck is called when GSCHub or the receiver confirms the delivery
+func (c *client) resolveAck(id string) {
+	c.mtxAcks.Lock()
+	defer c.mtxAcks.Unlock()
+	if chanAck, ok := c.acks[id]; ok {
+		close(chanAck)
+		delete(c.acks, id)
+	}
+}
+
+// sendAck confirms the delivery of message to its sender
+func (c *client) sendAck(msg *pb.Reply) error {
+	return c.sendLetter(c.ctx, &pb.Letter{
+		Type:     pb.Letter_Ack,
+		Receiver: msg.Sender,
+		Data:     []byte{},
+		ID:       msg.ID,
+	}, true)
+}
@@ -0,0 +1,117 @@
+package client
+
+import "sync"
+
+// EventHandlers are called when state of connection changes.
+// Handlers are called in order by a goroutine of the client which holds no locks,
+// so they may call methods of the client (e.g. Close), but later events wait for them.
+// Handlers may still be called after Close returns. Every handler is optional.
+type EventHandlers struct {
+	// OnConnected is called when connection is opened or re-opened
+	OnConnected func(connID string)
+	// OnDisconnected is called when connection is lost or the client is closed
+	OnDisconnected func(err error)
+	// OnReconnecting is called before every reconnecting attempt (starting at 1)
+	OnReconnecting func(attempt int)
+	// OnRenamed is called when alias name is changed by RenameConnection
+	OnRenamed func(aliasName string)
+	// OnConnIDChanged is called when GSCHub issues a new ClientTicket
+	// with a different connection's id after reconnecting
+	OnConnIDChanged func(oldConnID, newConnID string)
+}
+
+func (c *client) onConnected(connID string) {
+	if c.options.Events.OnConnected != nil {
+		handler := c.options.Events.OnConnected
+		c.emitEvent(func() { handler(connID) })
+	}
+}
+
+func (c *client) onDisconnected(err error) {
+	if c.options.Events.OnDisconnected != nil {
+		handler := c.options.Events.OnDisconnected
+		c.emitEvent(func() { handler(err) })
+	}
+}
+
+func (c *client) onReconnecting(attempt int) {
+	if c.options.Events.OnReconnecting != nil {
+		handler := c.options.Events.OnReconnecting
+		c.emitEvent(func() { handler(attempt) })
+	}
+}
+
+func (c *client) onRenamed(aliasName string) {
+	if c.options.Events.OnRenamed != nil {
+		handler := c.options.Events.OnRenamed
+		c.emitEvent(func() { handler(aliasName) })
+	}
+}
+
+func (c *client) onConnIDChanged(oldConnID, newConnID string) {
+	if c.options.Events.OnConnIDChanged != nil {
+		handler := c.options.Events.OnConnIDChanged
+		c.emitEvent(func() { handler(oldConnID, newConnID) })
+	}
+}
+
+// eventQueue keeps events until dispatchEvents calls their handlers
+type eventQueue struct {
+	once      sync.Once
+	mtx       sync.Mutex
+	handlers  []func()
+	isStopped bool
+	chanReady chan struct{}
+}
+
+// startEvents starts dispatching events, it is safe to be called many times
+func (c *client) startEvents() {
+	c.events.once.Do(func() {
+		go c.dispatchEvents()
+	})
+}
+
+// emitEvent queues handler, so it is never called while the client
+// holds its locks or by the goroutines waited by Shutdown
+func (c *client) emitEvent(handler func()) {
+	c.events.mtx.Lock()
+	if c.events.isStopped {
+		c.events.mtx.Unlock()
+		return
+	}
+	c.events.handlers = append(c.events.handlers, handler)
+	c.events.mtx.Unlock()
+
+	select {
+	case c.events.chanReady <- struct{}{}:
+	default:
+	}
+}
+
+// stopEvents makes dispatchEvents return after calling the queued handlers
+func (c *client) stopEvents() {
+	c.events.mtx.Lock()
+	c.events.isStopped = true
+	c.events.mtx.Unlock()
+
+	select {
+	case c.events.chanReady <- struct{}{}:
+	default:
+	}
+}
+
+func (c *client) dispatchEvents() {
+	for range c.events.chanReady {
+		c.events.mtx.Lock()
+		handlers, isStopped := c.events.handlers, c.events.isStopped
+		c.events.handlers = nil
+		c.events.mtx.Unlock()
+
+		for _, handler := range handlers {
+			handler()
+		}
+		if isStopped {
+			return
+		}
+	}
+}
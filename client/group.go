@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+
+	"github.com/gecosys/gsc-go/internal/randid"
+	pb "github.com/gecosys/gsc-go/message"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// SendGroupMessage sends data to every connection in the group
+func (c *client) SendGroupMessage(group string, data []byte, isEncrypted bool) error {
+	return c.SendGroupMessageContext(context.Background(), group, data, isEncrypted)
+}
+
+func (c *client) SendGroupMessageContext(ctx context.Context, group string, data []byte, isEncrypted bool) error {
+	return c.sendMessage(ctx, pb.Letter_Group, group, data, isEncrypted)
+}
+
+// JoinGroup adds the connection to the group
+func (c *client) JoinGroup(group string) error {
+	return c.JoinGroupContext(context.Background(), group)
+}
+
+func (c *client) JoinGroupContext(ctx context.Context, group string) error {
+	return c.sendMessage(ctx, pb.Letter_JoinGroup, group, []byte{}, true)
+}
+
+// LeaveGroup removes the connection from the group
+func (c *client) LeaveGroup(group string) error {
+	return c.LeaveGroupContext(context.Background(), group)
+}
+
+func (c *client) LeaveGroupContext(ctx context.Context, group string) error {
+	return c.sendMessage(ctx, pb.Letter_LeaveGroup, group, []byte{}, true)
+}
+
+// ListGroups returns names of groups which the connection joined,
+// it waits for the answer of GSCHub at most requestTimeout
+func (c *client) ListGroups() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return c.ListGroupsContext(ctx)
+}
+
+func (c *client) ListGroupsContext(ctx context.Context) ([]string, error) {
+	id, err := randid.New()
+	if err != nil {
+		return nil, err
+	}
+
+	// The answer is matched by ID, so a late answer of a request
+	// which timed out is not taken by the next request
+	chanGroups := make(chan *pb.GroupList, 1)
+	c.mtxGroups.Lock()
+	c.groupRequests[id] = chanGroups
+	c.mtxGroups.Unlock()
+	defer func() {
+		c.mtxGroups.Lock()
+		delete(c.groupRequests, id)
+		c.mtxGroups.Unlock()
+	}()
+
+	err = c.sendLetter(ctx, &pb.Letter{
+		Type:     pb.Letter_ListGroups,
+		Receiver: "",
+		Data:     []byte{},
+		ID:       id,
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case list := <-chanGroups:
+		return list.Groups, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// resolveGroups passes the answer of ListGroups to the request with the same ID,
+// answers of requests which are not waiting anymore are dropped
+func (c *client) resolveGroups(id string, data []byte) error {
+	list := new(pb.GroupList)
+	err := proto.Unmarshal(data, list)
+	if err != nil {
+		return err
+	}
+
+	c.mtxGroups.Lock()
+	chanGroups, ok := c.groupRequests[id]
+	c.mtxGroups.Unlock()
+	if ok {
+		select {
+		case chanGroups <- list:
+		default:
+		}
+	}
+	return nil
+}
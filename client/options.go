@@ -0,0 +1,127 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/gecosys/gsc-go/config"
+	"github.com/gecosys/gsc-go/outbox"
+	security "github.com/gecosys/gsc-go/security"
+	"github.com/gecosys/gsc-go/socket"
+)
+
+// DefaultPingInterval is the period between two pings sent to GSCHub
+const DefaultPingInterval = 1 * time.Second
+
+// Options configures a GEHClient created by New
+type Options struct {
+	// Config contains identity and address of GSCHub.
+	// If it is nil, config is loaded from config.DefaultPath when opening connection.
+	Config *config.Config
+
+	// PingInterval is the period between two pings (default: DefaultPingInterval)
+	PingInterval time.Duration
+
+	// ReconnectPolicy decides the delay between two reconnecting attempts
+	// and when to give up (default: retry every PingInterval forever)
+	ReconnectPolicy ReconnectPolicy
+
+	// Outbox keeps letters sent while the connection is lost,
+	// they are sent in order after reconnecting (default: no outbox, the letters are lost)
+	Outbox outbox.Outbox
+
+	// Events are called when state of connection changes
+	Events EventHandlers
+
+	// Cipher is algorithm used to encrypt payloads with the shared key
+	// of every connection (default: security.CipherAESCBC).
+	// AEAD suites (security.CipherAESGCM, security.CipherChaCha20Poly1305)
+	// reject tampered frames, they require support of GSCHub.
+	Cipher security.CipherSuite
+
+	// Handshake is how the shared key of every connection is established
+	// (default: security.HandshakeRSA). security.HandshakeX25519 gives forward secrecy,
+	// it requires GSCHub serving ephemeral keys at /ephemeral-key.
+	Handshake security.Handshake
+
+	// KeyWrap is algorithm used to encrypt the shared key and connection's id
+	// with the public key of GSCHub (default: security.KeyWrapOAEP).
+	// security.KeyWrapLegacy must be set explicitly for hubs older than VersionOAEP,
+	// here or by keyWrap of the config, it is never chosen by the version GSCHub announces.
+	KeyWrap security.KeyWrap
+
+	// Rand is source of randomness of security sessions (default: crypto/rand.Reader),
+	// it should only be replaced by deterministic readers in tests
+	Rand io.Reader
+
+	// SigningKey signs type, receiver, id, sequence and data of letters sent to connections and groups,
+	// so receivers can verify the author (default: letters are not signed)
+	SigningKey ed25519.PrivateKey
+
+	// SenderKeys resolves keys verifying signatures of received messages
+	// (default: signatures are not checked)
+	SenderKeys SenderKeys
+
+	// SignaturePolicy decides what to do with messages whose signature is not valid
+	// when SenderKeys is set (default: SignatureFlag)
+	SignaturePolicy SignaturePolicy
+
+	// FreshnessWindow is the maximum difference between timestamp of a received message
+	// and the local clock, other messages are rejected with ReplayError (default: not checked)
+	FreshnessWindow time.Duration
+
+	// ReplayProtection rejects messages whose sequence was already received from the same
+	// sender with ReplayError, and messages too old to be checked with SequenceTooOldError.
+	// Messages of senders which don't sequence their letters are passed.
+	ReplayProtection bool
+
+	// RekeyInterval rotates keys of the connection when it passes (default: not rotated by time)
+	RekeyInterval time.Duration
+
+	// RekeyAfterBytes rotates keys of the connection when the client wrote
+	// that many bytes with them (default: not rotated by size)
+	RekeyAfterBytes uint64
+
+	// RekeyGrace is the period messages under the replaced keys are still accepted
+	// (default: DefaultRekeyGrace)
+	RekeyGrace time.Duration
+
+	// TLSConfig is used for TLS of the HTTP handshake (https host) and of the socket,
+	// it replaces TLS of Config (default: TLS is used if Config.TLS is set)
+	TLSConfig *tls.Config
+
+	// Proxy returns the proxy to reach the URL of GSCHub like http.Transport.Proxy,
+	// it is used by the HTTP handshake and the socket. The socket is asked with scheme
+	// http (tcp, ws) or https (tls, wss), nil URL means no proxy (default: Config.Proxy).
+	Proxy func(*url.URL) (*url.URL, error)
+
+	// MaxFrameSize is the maximum size of frames received from GSCHub
+	// (default: socket.DefaultMaxFrameSize)
+	MaxFrameSize uint32
+
+	// FramePolicy decides what to do with larger frames: socket.FrameClose reconnects,
+	// socket.FrameDrop skips them and sends socket.FrameTooLargeError to the error
+	// channel of Listen (default: socket.FrameClose)
+	FramePolicy socket.FramePolicy
+}
+
+func (opts *Options) clone() *Options {
+	output := new(Options)
+	if opts != nil {
+		*output = *opts
+	}
+	if output.PingInterval <= 0 {
+		output.PingInterval = DefaultPingInterval
+	}
+	output.TLSConfig = cloneTLSConfig(output.TLSConfig)
+	if output.RekeyGrace <= 0 {
+		output.RekeyGrace = DefaultRekeyGrace
+	}
+	if output.ReconnectPolicy == nil {
+		output.ReconnectPolicy = &ConstantBackoff{Delay: output.PingInterval}
+	}
+	return output
+}
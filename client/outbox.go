@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+
+	pb "github.com/gecosys/gsc-go/message"
+	"github.com/gecosys/gsc-go/outbox"
+)
+
+// isQueueable reports whether letters of the type are kept in the outbox
+// while the connection is lost. Pings and requests waiting for answers are not.
+func isQueueable(letterType pb.Letter_Type) bool {
+	switch letterType {
+	case pb.Letter_Single, pb.Letter_Group, pb.Letter_JoinGroup, pb.Letter_LeaveGroup, pb.Letter_Ack:
+		return true
+	}
+	return false
+}
+
+// queueLetter pushes letter to the outbox if the connection is lost.
+// It returns false if the letter should be sent by the socket.
+func (c *client) queueLetter(letter *pb.Letter, isEncrypted bool) (bool, error) {
+	if c.options.Outbox == nil || isQueueable(letter.Type) == false {
+		return false, nil
+	}
+
+	c.mtxOutbox.Lock()
+	defer c.mtxOutbox.Unlock()
+	if atomic.LoadInt32(&c.isDisconnected) == 0 {
+		return false, nil
+	}
+	return true, c.pushOutbox(letter, isEncrypted)
+}
+
+func (c *client) pushOutbox(letter *pb.Letter, isEncrypted bool) error {
+	return c.options.Outbox.Push(&outbox.Entry{
+		Letter:      letter,
+		IsEncrypted: isEncrypted,
+	})
+}
+
+// markConnected replays letters of the outbox in order and then lets
+// new letters go to the socket directly, replaying is aborted when ctx is done
+func (c *client) markConnected(ctx context.Context) error {
+	c.mtxOutbox.Lock()
+	defer c.mtxOutbox.Unlock()
+
+	if c.options.Outbox != nil {
+		for {
+			entry, err := c.options.Outbox.Peek()
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				break
+			}
+			err = c.writeLetter(ctx, entry.Letter, entry.IsEncrypted)
+			if err != nil {
+				return err
+			}
+			err = c.options.Outbox.Pop()
+			if err != nil {
+				return err
+			}
+			c.resolveWritten(entry.Letter.ID)
+		}
+	}
+	atomic.StoreInt32(&c.isDisconnected, 0)
+	return nil
+}
+
+// waitWritten registers the letter, the returned channel is closed
+// when the letter is written to the socket by replaying the outbox
+func (c *client) waitWritten(id string) chan struct{} {
+	c.mtxOutbox.Lock()
+	defer c.mtxOutbox.Unlock()
+	chanWritten := make(chan struct{})
+	c.written[id] = chanWritten
+	return chanWritten
+}
+
+func (c *client) cancelWritten(id string) {
+	c.mtxOutbox.Lock()
+	defer c.mtxOutbox.Unlock()
+	delete(c.written, id)
+}
+
+// resolveWritten is called with mtxOutbox locked when a letter of the outbox is written
+func (c *client) resolveWritten(id string) {
+	if chanWritten, ok := c.written[id]; ok {
+		close(chanWritten)
+		delete(c.written, id)
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ReconnectPolicy decides how the client reconnects to GSCHub after losing connection
+type ReconnectPolicy interface {
+	// NextDelay returns the delay before the next reconnecting attempt.
+	// attempt is the number of failed attempts (starting at 1) and elapsed is
+	// the time since the connection was lost.
+	// If ok is false, the client gives up reconnecting.
+	NextDelay(attempt int, elapsed time.Duration) (delay time.Duration, ok bool)
+}
+
+// ConstantBackoff retries after the same delay
+type ConstantBackoff struct {
+	// Delay between two attempts (default: DefaultPingInterval)
+	Delay time.Duration
+	// MaxAttempts is the limit of failed attempts, 0 means no limit
+	MaxAttempts int
+	// MaxElapsedTime is the limit of time to reconnect, 0 means no limit
+	MaxElapsedTime time.Duration
+}
+
+// NextDelay implements ReconnectPolicy
+func (b *ConstantBackoff) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if exceeded(attempt, elapsed, b.MaxAttempts, b.MaxElapsedTime) {
+		return 0, false
+	}
+	if b.Delay <= 0 {
+		return DefaultPingInterval, true
+	}
+	return b.Delay, true
+}
+
+// ExponentialBackoff multiplies the delay after every failed attempt
+// and randomizes it, so many clients don't reconnect at the same time
+type ExponentialBackoff struct {
+	// InitialDelay is the delay after the first failed attempt (default: 1 second)
+	InitialDelay time.Duration
+	// MaxDelay is the limit of delay (default: 1 minute)
+	MaxDelay time.Duration
+	// Multiplier is the growth of delay after every attempt (default: 2)
+	Multiplier float64
+	// Jitter randomizes the delay in range [delay*(1-Jitter), delay*(1+Jitter)],
+	// it should be in range [0, 1]
+	Jitter float64
+	// MaxAttempts is the limit of failed attempts, 0 means no limit
+	MaxAttempts int
+	// MaxElapsedTime is the limit of time to reconnect, 0 means no limit
+	MaxElapsedTime time.Duration
+}
+
+// NextDelay implements ReconnectPolicy
+func (b *ExponentialBackoff) NextDelay(attempt int, elapsed time.Duration) (time.Duration, bool) {
+	if exceeded(attempt, elapsed, b.MaxAttempts, b.MaxElapsedTime) {
+		return 0, false
+	}
+
+	var (
+		initialDelay = b.InitialDelay
+		maxDelay     = b.MaxDelay
+		multiplier   = b.Multiplier
+	)
+	if initialDelay <= 0 {
+		initialDelay = 1 * time.Second
+	}
+	if maxDelay <= 0 {
+		maxDelay = 1 * time.Minute
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(initialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if delay > float64(maxDelay) {
+		delay = float64(maxDelay)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*randomFloat() - 1)
+	}
+	return time.Duration(delay), true
+}
+
+// randomFloat returns a number in range [0, 1). It reads crypto/rand, because
+// math/rand is seeded with the same value in every process before Go 1.20
+// and clients would compute the same delays.
+func randomFloat() float64 {
+	var buffer [8]byte
+	_, err := rand.Read(buffer[:])
+	if err != nil {
+		return 0.5
+	}
+	return float64(binary.BigEndian.Uint64(buffer[:])>>11) / (1 << 53)
+}
+
+func exceeded(attempt int, elapsed time.Duration, maxAttempts int, maxElapsedTime time.Duration) bool {
+	if maxAttempts > 0 && attempt >= maxAttempts {
+		return true
+	}
+	return maxElapsedTime > 0 && elapsed >= maxElapsedTime
+}
+
+// ReconnectError is sent to the error channel of Listen when the client
+// gives up reconnecting, the client is closed after that
+type ReconnectError struct {
+	Attempts int
+	Elapsed  time.Duration
+	// Err is the error of the last attempt
+	Err error
+}
+
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("Gave up reconnecting after %d attempts (%s): %v", e.Attempts, e.Elapsed, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *ReconnectError) Unwrap() error {
+	return e.Err
+}
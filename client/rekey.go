@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/gecosys/gsc-go/internal/randid"
+	pb "github.com/gecosys/gsc-go/message"
+	security "github.com/gecosys/gsc-go/security"
+	"github.com/gecosys/gsc-go/socket"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// DefaultRekeyGrace is the period messages under the replaced keys are still accepted
+const DefaultRekeyGrace = 10 * time.Second
+
+// Rekey installs a new shared key and secret key without reconnecting,
+// it waits for the answer of GSCHub at most requestTimeout
+func (c *client) Rekey() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return c.RekeyContext(ctx)
+}
+
+// rekeyRequest is a rekey waiting for the answer of GSCHub,
+// it is guarded by mtxConn
+type rekeyRequest struct {
+	id       string
+	socket   socket.GEHSocket
+	session  *security.Session
+	chanDone chan struct{}
+}
+
+// RekeyContext installs a new shared key and secret key without reconnecting.
+// The socket and ConnID are kept, messages under the previous keys
+// are still accepted during Options.RekeyGrace.
+// If the answer of GSCHub doesn't come in time, the connection is closed and opened
+// again, because GSCHub may already use the new keys.
+func (c *client) RekeyContext(ctx context.Context) error {
+	c.mtxRekey.Lock()
+	defer c.mtxRekey.Unlock()
+
+	socket := c.getSocket()
+	if socket == nil {
+		return ErrNotConnected
+	}
+
+	session, err := c.setupSecurity(ctx, c.hubURL)
+	if err != nil {
+		return err
+	}
+	sharedKey, err := c.buildSharedKey(session)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(sharedKey)
+	if err != nil {
+		return err
+	}
+	id, err := randid.New()
+	if err != nil {
+		return err
+	}
+
+	req := &rekeyRequest{
+		id:       id,
+		socket:   socket,
+		session:  session,
+		chanDone: make(chan struct{}),
+	}
+	c.mtxConn.Lock()
+	c.rekeyRequest = req
+	c.mtxConn.Unlock()
+	// Messages sent by GSCHub right after its answer are under the new keys
+	socket.SetPendingSession(session)
+
+	// The letter is encrypted with the current key, GSCHub answers with it too.
+	// The answer is installed by receiveLoop before the next message is read.
+	err = c.sendLetter(ctx, &pb.Letter{
+		Type:     pb.Letter_Rekey,
+		Receiver: "",
+		Data:     data,
+		ID:       id,
+	}, true)
+	if err == nil {
+		select {
+		case <-req.chanDone:
+			return nil
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-c.ctx.Done():
+			err = ErrClosed
+		}
+	}
+
+	c.mtxConn.Lock()
+	defer c.mtxConn.Unlock()
+	if c.rekeyRequest != req {
+		// The answer came in the meantime
+		return nil
+	}
+	c.rekeyRequest = nil
+	if c.socket != socket {
+		// The client reconnected, so the connection already has new keys
+		return nil
+	}
+	// GSCHub may have switched to the new keys, so the keys of both sides
+	// are only known to match after reconnecting
+	socket.Close()
+	return err
+}
+
+// resolveRekey installs the keys of the request with the same ID,
+// it is called by receiveLoop, so messages after the answer are checked with the new keys
+func (c *client) resolveRekey(id string, secretKey []byte) {
+	c.mtxConn.Lock()
+	defer c.mtxConn.Unlock()
+	req := c.rekeyRequest
+	if req == nil || req.id != id || req.socket != c.socket {
+		return
+	}
+
+	key := string(secretKey)
+	if key == "" {
+		key = c.socket.GetSecretKey()
+	}
+	c.socket.RotateKeys(req.session, key, c.options.RekeyGrace)
+	c.session = req.session
+	c.resetRekeyCounters()
+	c.rekeyRequest = nil
+	close(req.chanDone)
+}
+
+// resetRekeyCounters is called with mtxConn locked when a session is installed
+func (c *client) resetRekeyCounters() {
+	c.sessionAt = time.Now()
+	atomic.StoreUint64(&c.writtenBytes, 0)
+}
+
+// isRekeyDue reports whether the session reached Options.RekeyInterval or Options.RekeyAfterBytes
+func (c *client) isRekeyDue() bool {
+	if c.options.RekeyAfterBytes > 0 && atomic.LoadUint64(&c.writtenBytes) >= c.options.RekeyAfterBytes {
+		return true
+	}
+	if c.options.RekeyInterval <= 0 {
+		return false
+	}
+	c.mtxConn.RLock()
+	defer c.mtxConn.RUnlock()
+	return time.Since(c.sessionAt) >= c.options.RekeyInterval
+}
+
+// rekeyIfDue rotates the keys automatically, it is called by loopAction
+// so the error is reported from another goroutine, nobody may be listening
+func (c *client) rekeyIfDue() {
+	if c.isRekeyDue() == false {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, requestTimeout)
+	defer cancel()
+	err := c.RekeyContext(ctx)
+	if err != nil && c.ctx.Err() == nil {
+		c.wgLoop.Add(1)
+		go func() {
+			defer c.wgLoop.Done()
+			c.emitError(err)
+		}()
+	}
+}
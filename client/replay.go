@@ -0,0 +1,181 @@
+package client
+
+import (
+	"time"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+// ReplayWindowSize is number of recent sequence numbers remembered per sender and group
+const ReplayWindowSize = 64
+
+// maxReplaySenders limits number of senders whose windows are remembered,
+// the least recently seen sender is forgotten when it is reached
+const maxReplaySenders = 4096
+
+// maxSequences limits number of receivers whose sequences are remembered,
+// a forgotten receiver starts again from the clock
+const maxSequences = 4096
+
+// ReplayError is sent to the error channel of Listen when a message is stale or replayed
+type ReplayError struct {
+	ID        string
+	Sender    string
+	Timestamp int32
+	Sequence  uint64
+	// Stale is true if the timestamp is out of Options.FreshnessWindow,
+	// otherwise the sequence was already received
+	Stale bool
+}
+
+func (e *ReplayError) Error() string {
+	if e.Stale {
+		return "Message from " + e.Sender + " is stale"
+	}
+	return "Message from " + e.Sender + " is replayed"
+}
+
+// SequenceTooOldError is sent to the error channel of Listen when the sequence of a message
+// is ReplayWindowSize or more below the highest sequence received from its sender,
+// so it can't be told whether the message was received. It is not acknowledged.
+type SequenceTooOldError struct {
+	ID       string
+	Sender   string
+	Group    string
+	Sequence uint64
+	Highest  uint64
+}
+
+func (e *SequenceTooOldError) Error() string {
+	return "Message from " + e.Sender + " is too old to be checked for replay"
+}
+
+// replayVerdict is result of checking a sequence against a replay window
+type replayVerdict int
+
+const (
+	replayAccepted replayVerdict = iota
+	replayDuplicate
+	replayTooOld
+)
+
+// sequenceKey identifies the receiver of letters sharing a sequence
+type sequenceKey struct {
+	letterType pb.Letter_Type
+	receiver   string
+}
+
+// replayKey identifies letters of a sender sharing a sequence on the receiver's side
+type replayKey struct {
+	sender string
+	group  string
+}
+
+// replayWindow remembers the highest sequence received from a sender
+// and which of the previous ReplayWindowSize sequences were received
+type replayWindow struct {
+	highest  uint64
+	bitmap   uint64
+	lastSeen time.Time
+}
+
+// accept marks seq as received if it was not received
+// and it is not too old to be checked
+func (w *replayWindow) accept(seq uint64) replayVerdict {
+	if seq > w.highest {
+		shift := seq - w.highest
+		if shift >= ReplayWindowSize {
+			w.bitmap = 1
+		} else {
+			w.bitmap = w.bitmap<<shift | 1
+		}
+		w.highest = seq
+		return replayAccepted
+	}
+
+	offset := w.highest - seq
+	if offset >= ReplayWindowSize {
+		return replayTooOld
+	}
+	mask := uint64(1) << offset
+	if w.bitmap&mask != 0 {
+		return replayDuplicate
+	}
+	w.bitmap |= mask
+	return replayAccepted
+}
+
+// sequenceLetter gives letter the next sequence of its receiver. Every receiver and group
+// has its own sequence, so letters sent to others don't push it out of the window of the receiver.
+// Letters sent again (retries of acknowledgement, outbox) keep their sequence.
+func (c *client) sequenceLetter(letter *pb.Letter) {
+	if letter.Sequence != 0 || isSignable(letter.Type) == false {
+		return
+	}
+
+	key := sequenceKey{letterType: letter.Type, receiver: letter.Receiver}
+	c.mtxSequences.Lock()
+	defer c.mtxSequences.Unlock()
+	seq, ok := c.sequences[key]
+	if ok == false {
+		if len(c.sequences) >= maxSequences {
+			for oldKey := range c.sequences {
+				delete(c.sequences, oldKey)
+				break
+			}
+		}
+		// Sequences start from the clock, so they still increase after restarting
+		seq = uint64(time.Now().UnixNano())
+	}
+	seq++
+	c.sequences[key] = seq
+	letter.Sequence = seq
+}
+
+// checkReplay returns ReplayError if msg is stale or was already received,
+// and SequenceTooOldError if it can't be checked.
+// It is only called by receiveLoop, so windows are not locked.
+func (c *client) checkReplay(msg *pb.Reply) error {
+	if c.options.FreshnessWindow > 0 {
+		diff := time.Since(time.Unix(int64(msg.Timestamp), 0))
+		if diff > c.options.FreshnessWindow || diff < -c.options.FreshnessWindow {
+			return &ReplayError{ID: msg.ID, Sender: msg.Sender, Timestamp: msg.Timestamp, Sequence: msg.Sequence, Stale: true}
+		}
+	}
+
+	// Senders which don't sequence their letters can't be checked
+	if c.options.ReplayProtection == false || msg.Sequence == 0 {
+		return nil
+	}
+
+	key := replayKey{sender: msg.Sender, group: msg.Group}
+	window, ok := c.replayWindows[key]
+	if ok == false {
+		if len(c.replayWindows) >= maxReplaySenders {
+			c.forgetOldestSender()
+		}
+		window = new(replayWindow)
+		c.replayWindows[key] = window
+	}
+	window.lastSeen = time.Now()
+	switch window.accept(msg.Sequence) {
+	case replayDuplicate:
+		return &ReplayError{ID: msg.ID, Sender: msg.Sender, Timestamp: msg.Timestamp, Sequence: msg.Sequence}
+	case replayTooOld:
+		return &SequenceTooOldError{ID: msg.ID, Sender: msg.Sender, Group: msg.Group, Sequence: msg.Sequence, Highest: window.highest}
+	}
+	return nil
+}
+
+func (c *client) forgetOldestSender() {
+	var (
+		oldest   replayKey
+		lastSeen time.Time
+	)
+	for key, window := range c.replayWindows {
+		if lastSeen.IsZero() || window.lastSeen.Before(lastSeen) {
+			oldest, lastSeen = key, window.lastSeen
+		}
+	}
+	delete(c.replayWindows, oldest)
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"testing"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+func TestReplayWindowAccept(t *testing.T) {
+	w := new(replayWindow)
+	steps := []struct {
+		seq  uint64
+		want replayVerdict
+	}{
+		{100, replayAccepted},
+		{100, replayDuplicate},
+		{101, replayAccepted},
+		{99, replayAccepted}, // late but inside the window
+		{99, replayDuplicate},
+		{101 - ReplayWindowSize + 1, replayAccepted}, // the oldest sequence inside the window
+		{101 - ReplayWindowSize, replayTooOld},
+		{200, replayAccepted}, // the window slides past every previous sequence
+		{150, replayAccepted},
+		{150, replayDuplicate},
+		{101, replayTooOld},
+		{200 + ReplayWindowSize, replayAccepted},
+		{201, replayAccepted},
+		{200, replayTooOld},
+	}
+	for idx, step := range steps {
+		got := w.accept(step.seq)
+		if got != step.want {
+			t.Fatalf("step %d: accept(%d) = %d, want %d", idx, step.seq, got, step.want)
+		}
+	}
+}
+
+func TestReplayWindowShift(t *testing.T) {
+	// Sequences received before sliding by less than the window size are remembered
+	w := new(replayWindow)
+	for seq := uint64(1); seq <= 10; seq++ {
+		w.accept(seq)
+	}
+	if got := w.accept(10 + ReplayWindowSize - 1); got != replayAccepted {
+		t.Fatalf("accept() = %d, want accepted", got)
+	}
+	if got := w.accept(10); got != replayDuplicate {
+		t.Fatalf("accept(10) = %d, want duplicate", got)
+	}
+	if got := w.accept(9); got != replayTooOld {
+		t.Fatalf("accept(9) = %d, want too old", got)
+	}
+}
+
+func TestSequencePerReceiver(t *testing.T) {
+	c := newClient(nil)
+	letter := func(letterType pb.Letter_Type, receiver string) uint64 {
+		l := &pb.Letter{Type: letterType, Receiver: receiver}
+		c.sequenceLetter(l)
+		return l.Sequence
+	}
+
+	first := letter(pb.Letter_Single, "a")
+	for idx := 0; idx < 2*ReplayWindowSize; idx++ {
+		letter(pb.Letter_Single, "b")
+		letter(pb.Letter_Group, "a")
+	}
+	if got := letter(pb.Letter_Single, "a"); got != first+1 {
+		t.Fatalf("sequence of a = %d, want %d", got, first+1)
+	}
+
+	// Letters sent again keep their sequence, other letters are not sequenced
+	l := &pb.Letter{Type: pb.Letter_Single, Receiver: "a", Sequence: 7}
+	c.sequenceLetter(l)
+	if l.Sequence != 7 {
+		t.Fatalf("sequence of resent letter = %d, want 7", l.Sequence)
+	}
+	if got := letter(pb.Letter_JoinGroup, "a"); got != 0 {
+		t.Fatalf("sequence of JoinGroup = %d, want 0", got)
+	}
+}
+
+func TestCheckReplay(t *testing.T) {
+	c := newClient(&Options{ReplayProtection: true})
+	reply := func(sender, group string, seq uint64) error {
+		return c.checkReplay(&pb.Reply{Sender: sender, Group: group, Sequence: seq})
+	}
+
+	if err := reply("a", "", 1000); err != nil {
+		t.Fatal(err)
+	}
+	// Groups of a sender have their own windows
+	if err := reply("a", "g", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reply("a", "", 1000).(*ReplayError); ok == false {
+		t.Fatal("replayed message is not rejected with ReplayError")
+	}
+	if _, ok := reply("a", "", 1000-ReplayWindowSize).(*SequenceTooOldError); ok == false {
+		t.Fatal("too old message is not rejected with SequenceTooOldError")
+	}
+	// Senders which don't sequence their letters are not checked
+	if err := reply("a", "", 0); err != nil {
+		t.Fatal(err)
+	}
+}
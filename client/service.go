@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -25,80 +26,309 @@ import (
 // Version is version of hub
 const Version = "2.2.0"
 
-var once sync.Once
+// VersionOAEP is the first version of hub which wraps keys with RSA-OAEP
+const VersionOAEP = "3.0.0"
+
+// httpTimeout is the limit of time for requests sent to GSCHub by HTTP
+const httpTimeout = 5 * time.Second
+
+// requestTimeout is the limit of time to wait for answers of GSCHub
+// when the caller doesn't give a context
+const requestTimeout = 5 * time.Second
+
+var mtxInstance sync.Mutex
 var instance *client
 
+var (
+	// ErrClosed is returned when the client has been closed
+	ErrClosed = errors.New("Client is closed")
+	// ErrNotConnected is returned when sending before the connection is opened
+	ErrNotConnected = errors.New("Connection is not opened")
+	// ErrInvalidMessage is sent to the error channel of Listen when HMAC of a message is wrong
+	ErrInvalidMessage = errors.New("Invalid message")
+)
+
 // GEHMessage is message received from Goldeneye Hubs System
 type GEHMessage struct {
-	Sender    string
+	// ID is id of the message, it is empty if the sender doesn't ask for acknowledgement
+	ID     string
+	Sender string
+	// Group is name of the group which the message was sent to,
+	// it is empty if the message was sent to this connection only
+	Group     string
 	Data      []byte
 	Timestamp int32
+	// Signature is result of verifying signature of the message, see Options.SenderKeys
+	Signature SignatureState
 }
 
 // GEHClient is client which communicates with Goldeneye Hubs System
 type GEHClient interface {
 	OpenConn(aliasName string) error
+	OpenConnContext(ctx context.Context, aliasName string) error
 	Listen() (chan *GEHMessage, chan error)
 	SendMessage(receiver string, data []byte, isEncrypted bool) error
+	SendMessageContext(ctx context.Context, receiver string, data []byte, isEncrypted bool) error
 	RenameConnection(aliasName string) error
+	RenameConnectionContext(ctx context.Context, aliasName string) error
+	SendGroupMessage(group string, data []byte, isEncrypted bool) error
+	SendGroupMessageContext(ctx context.Context, group string, data []byte, isEncrypted bool) error
+	JoinGroup(group string) error
+	JoinGroupContext(ctx context.Context, group string) error
+	LeaveGroup(group string) error
+	LeaveGroupContext(ctx context.Context, group string) error
+	ListGroups() ([]string, error)
+	ListGroupsContext(ctx context.Context) ([]string, error)
+	SendMessageWithAck(ctx context.Context, receiver string, data []byte, isEncrypted bool, opts *AckOptions) (string, error)
+	Rekey() error
+	RekeyContext(ctx context.Context) error
+	Close() error
+	Shutdown(ctx context.Context) error
 
 	GetID() string
 	GetVersion() string
 	GetAliasName() string
 }
 
-// GetClient returns shared instance of GEHClient
+// GetClient returns shared instance of GEHClient.
+// The instance uses config loaded from config.DefaultPath.
+// After the instance is closed, the next call returns a new instance.
 func GetClient() GEHClient {
-	if instance != nil {
-		return instance
+	mtxInstance.Lock()
+	defer mtxInstance.Unlock()
+	if instance == nil || instance.closed() {
+		instance = newClient(nil)
 	}
-	once.Do(func() {
-		instance = new(client)
-		instance.isOpen = false
-	})
 	return instance
 }
 
+// New creates a GEHClient which has its own config, socket and reconnecting loop,
+// so many clients with different identities can be used in the same process
+func New(opts *Options) GEHClient {
+	return newClient(opts)
+}
+
+func newClient(opts *Options) *client {
+	c := new(client)
+	c.options = opts.clone()
+	c.isOpen = false
+	c.httpClient = &http.Client{
+		Timeout: httpTimeout,
+	}
+	c.chanMessage = make(chan *GEHMessage)
+	c.chanError = make(chan error)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.chanReconnected = make(chan struct{}, 1)
+	c.groupRequests = make(map[string]chan *pb.GroupList)
+	c.acks = make(map[string]chan struct{})
+	c.written = make(map[string]chan struct{})
+	c.replayWindows = make(map[replayKey]*replayWindow)
+	c.sequences = make(map[sequenceKey]uint64)
+	c.events.chanReady = make(chan struct{}, 1)
+	// Letters are queued until the outbox is drained by OpenConn
+	c.isDisconnected = 1
+	return c
+}
+
 type client struct {
-	isOpen              bool
-	config              *config.Config
-	clientInfo          *pb.Client
-	clientTicket        *pb.ClientTicket
-	socket              socket.GEHSocket
-	isDisconnected      int32
-	waitForReconnecting sync.WaitGroup
+	writtenBytes    uint64 // bytes written with the current session, first so it is aligned for atomic operations
+	mtxOpen         sync.Mutex
+	isOpen          bool
+	options         *Options
+	config          *config.Config
+	trust           *security.Trust
+	keyWrap         security.KeyWrap
+	httpClient      *http.Client
+	socketOptions   *socket.Options
+	hubURL          string // base URL of the HTTP handshake
+	clientInfo      *pb.Client
+	mtxConn         sync.RWMutex
+	clientTicket    *pb.ClientTicket
+	session         *security.Session
+	socket          socket.GEHSocket
+	isDisconnected  int32
+	chanReconnected chan struct{}
+	chanMessage     chan *GEHMessage
+	chanError       chan error
+	mtxState        sync.RWMutex
+	isClosed        bool
+	ctx             context.Context // done when the client is closed
+	cancel          context.CancelFunc
+	wgSend          sync.WaitGroup
+	wgLoop          sync.WaitGroup
+	mtxGroups       sync.Mutex
+	groupRequests   map[string]chan *pb.GroupList
+	mtxAcks         sync.Mutex
+	acks            map[string]chan struct{}
+	mtxOutbox       sync.Mutex
+	written         map[string]chan struct{} // letters waiting in the outbox, guarded by mtxOutbox
+	replayWindows   map[replayKey]*replayWindow
+	mtxSequences    sync.Mutex
+	sequences       map[sequenceKey]uint64
+	mtxRekey        sync.Mutex
+	rekeyRequest    *rekeyRequest
+	sessionAt       time.Time // when the current session was installed
+	events          eventQueue
 }
 
 // OpenConn opens connection to GSCHub
 func (c *client) OpenConn(aliasName string) error {
+	return c.OpenConnContext(context.Background(), aliasName)
+}
+
+// OpenConnContext opens connection to GSCHub,
+// the handshake is aborted when ctx is done
+func (c *client) OpenConnContext(ctx context.Context, aliasName string) error {
+	c.mtxOpen.Lock()
+	defer c.mtxOpen.Unlock()
 	if c.isOpen {
 		return nil
 	}
+	if c.closed() {
+		return ErrClosed
+	}
 	c.isOpen = true
+	c.startEvents()
 
-	conf, err := config.GetConfig()
+	conf, err := c.loadConfig()
 	if err != nil {
 		c.isOpen = false
 		return err
 	}
 	c.config = conf
 
+	c.keyWrap, err = c.resolveKeyWrap(conf)
+	if err != nil {
+		c.isOpen = false
+		return err
+	}
+
+	c.trust, err = security.NewTrust(conf.HubKeyFingerprint, conf.HubKeyPEM, conf.HubSigningKey)
+	if err != nil {
+		c.isOpen = false
+		return err
+	}
+
+	err = c.setupTransport(conf)
+	if err != nil {
+		c.isOpen = false
+		return err
+	}
+
+	c.mtxConn.Lock()
 	c.clientInfo = &pb.Client{
 		ID:        conf.ID,
 		Token:     conf.Token,
 		AliasName: aliasName,
 	}
+	c.mtxConn.Unlock()
+
+	err = c.connect(ctx)
+	if err != nil {
+		c.isOpen = false
+		return err
+	}
 
-	err = c.connect()
+	// Letters left in the outbox by an earlier run are sent before new ones
+	err = c.markConnected(ctx)
 	if err != nil {
 		c.isOpen = false
+		c.getSocket().Close()
 		return err
 	}
+
+	c.mtxState.RLock()
+	defer c.mtxState.RUnlock()
+	if c.isClosed {
+		c.isOpen = false
+		c.getSocket().Close()
+		return ErrClosed
+	}
+	c.wgLoop.Add(2)
 	go c.loopAction()
+	go c.receiveLoop()
+	c.onConnected(c.GetID())
 	return nil
 }
 
+// Close closes the client, it is the same as Shutdown without limit of time
+func (c *client) Close() error {
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown stops pinging and reconnecting, waits for pending sends,
+// closes the socket and then the channels returned by Listen.
+// If ctx is done before the pending sends finish,
+// the socket is closed anyway and ctx.Err() is returned.
+func (c *client) Shutdown(ctx context.Context) error {
+	c.mtxState.Lock()
+	if c.isClosed {
+		c.mtxState.Unlock()
+		return ErrClosed
+	}
+	c.isClosed = true
+	c.cancel()
+	c.mtxState.Unlock()
+
+	// Drain pending sends
+	var (
+		err      error
+		chanSent = make(chan struct{})
+	)
+	go func() {
+		c.wgSend.Wait()
+		close(chanSent)
+	}()
+	select {
+	case <-chanSent:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// Make sure no connection is being opened
+	c.mtxOpen.Lock()
+	isOpen := c.isOpen
+	c.mtxOpen.Unlock()
+	if socket := c.getSocket(); socket != nil {
+		socket.Close()
+	}
+	c.wgLoop.Wait()
+	<-chanSent
+
+	// The reconnecting loop could replace the socket before stopping
+	if socket := c.getSocket(); socket != nil {
+		socket.Close()
+	}
+
+	close(c.chanMessage)
+	close(c.chanError)
+	if isOpen {
+		c.onDisconnected(ErrClosed)
+	}
+	c.stopEvents()
+	return err
+}
+
+func (c *client) closed() bool {
+	c.mtxState.RLock()
+	defer c.mtxState.RUnlock()
+	return c.isClosed
+}
+
+func (c *client) loadConfig() (*config.Config, error) {
+	if c.options.Config != nil {
+		return c.options.Config, nil
+	}
+	return config.GetConfig()
+}
+
+// GetID returns id of the connection, it is empty before the connection is opened
 func (c *client) GetID() string {
+	c.mtxConn.RLock()
+	defer c.mtxConn.RUnlock()
+	if c.clientTicket == nil {
+		return ""
+	}
 	return c.clientTicket.ConnID
 }
 
@@ -106,7 +336,13 @@ func (c *client) GetVersion() string {
 	return Version
 }
 
+// GetAliasName returns alias name of the connection, it is empty before the connection is opened
 func (c *client) GetAliasName() string {
+	c.mtxConn.RLock()
+	defer c.mtxConn.RUnlock()
+	if c.clientInfo == nil {
+		return ""
+	}
 	return c.clientInfo.AliasName
 }
 
@@ -116,76 +352,225 @@ func calcHMAC(key string, data []byte) []byte {
 	return h.Sum(nil)
 }
 
+// Listen returns channels of messages and errors received from GSCHub.
+// Every call returns the same channels, they are closed when the client is closed.
+// The channels have a single consumer: a client used by rpc.Peer or e2e.Peer
+// must not be read directly or shared with another peer, use Listen of the peer instead.
 func (c *client) Listen() (chan *GEHMessage, chan error) {
-	var chanError = make(chan error)
-	var chanMessage = make(chan *GEHMessage)
-	go func(chanMessage chan *GEHMessage, chanError chan error) {
-		var chanClientMessage = c.socket.ListenMessage()
-		for {
-			msg, ok := <-chanClientMessage
-			if !ok {
-				c.waitForReconnecting.Add(1)
-				atomic.StoreInt32(&c.isDisconnected, 1)
-				c.waitForReconnecting.Wait()
-				chanClientMessage = c.socket.ListenMessage()
-				continue
-			}
+	return c.chanMessage, c.chanError
+}
 
-			if c.validateMessage(msg.HMAC, msg.Data) == false {
-				chanError <- errors.New("Invalid message")
-				continue
+// receiveLoop reads messages from the current socket and waits for
+// reconnecting when the socket is closed
+func (c *client) receiveLoop() {
+	defer c.wgLoop.Done()
+	for {
+		socket := c.getSocket()
+		chanReply, chanSocketError := socket.ListenMessage()
+		for chanReply != nil {
+			select {
+			case msg, ok := <-chanReply:
+				if ok == false {
+					chanReply = nil
+					continue
+				}
+				if c.validateMessage(msg.HMAC, msg.Data) == false {
+					c.emitError(ErrInvalidMessage)
+					continue
+				}
+				c.handleReply(msg)
+			case err, ok := <-chanSocketError:
+				if ok == false {
+					chanSocketError = nil
+					continue
+				}
+				// Tampered or malformed frames are dropped
+				c.emitError(err)
 			}
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		default:
+		}
+		atomic.StoreInt32(&c.isDisconnected, 1)
+		c.onDisconnected(socket.Err())
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.chanReconnected:
+		}
+	}
+}
 
-			chanMessage <- &GEHMessage{
-				Sender:    msg.Sender,
-				Data:      msg.Data,
-				Timestamp: msg.Timestamp,
+// handleReply passes answers of GSCHub to the waiting requests
+// and messages of other connections to the channel of Listen
+func (c *client) handleReply(msg *pb.Reply) {
+	switch msg.Type {
+	case pb.Letter_ListGroups:
+		err := c.resolveGroups(msg.ID, msg.Data)
+		if err != nil {
+			c.emitError(err)
+		}
+	case pb.Letter_Ack:
+		c.resolveAck(msg.ID)
+	case pb.Letter_Rekey:
+		c.resolveRekey(msg.ID, msg.Data)
+	default:
+		state := c.verifyReply(msg)
+		if c.options.SignaturePolicy == SignatureReject && state != SignatureValid && state != SignatureNotChecked {
+			c.emitError(&SignatureError{ID: msg.ID, Sender: msg.Sender, State: state})
+			return
+		}
+		err := c.checkReplay(msg)
+		if err != nil {
+			// The sender may send the message again because it missed the acknowledgement,
+			// messages too old to be checked may have never been received, so they are not acknowledged
+			replayErr, ok := err.(*ReplayError)
+			if ok && replayErr.Stale == false && msg.Ack == pb.Letter_AckReceiver && msg.ID != "" {
+				c.sendAck(msg)
+			}
+			c.emitError(err)
+			return
+		}
+		if msg.Ack == pb.Letter_AckReceiver && msg.ID != "" {
+			err := c.sendAck(msg)
+			if err != nil {
+				c.emitError(err)
 			}
 		}
-	}(chanMessage, chanError)
-	return chanMessage, chanError
+		c.emitMessage(&GEHMessage{
+			ID:        msg.ID,
+			Sender:    msg.Sender,
+			Group:     msg.Group,
+			Data:      msg.Data,
+			Timestamp: msg.Timestamp,
+			Signature: state,
+		})
+	}
+}
+
+func (c *client) emitMessage(msg *GEHMessage) {
+	select {
+	case c.chanMessage <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+func (c *client) emitError(err error) {
+	select {
+	case c.chanError <- err:
+	case <-c.ctx.Done():
+	}
 }
 
 func (c *client) loopAction() {
+	defer c.wgLoop.Done()
 	var (
+		err          error
+		attempt      int
+		lostAt       time.Time
+		delay        time.Duration
+		ok           bool
 		timer        *time.Timer
-		timeDuration = 1 * time.Second
+		timeDuration = c.options.PingInterval
 	)
 	timer = time.NewTimer(timeDuration)
+	defer timer.Stop()
 
-	for range timer.C {
-		if atomic.LoadInt32(&c.isDisconnected) == 1 {
-			if c.connect() != nil {
-				timer.Reset(timeDuration)
-				continue
-			}
-			if c.ping() == nil {
-				atomic.StoreInt32(&c.isDisconnected, 0)
-				c.waitForReconnecting.Done()
-			}
-		} else {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if atomic.LoadInt32(&c.isDisconnected) == 0 {
 			c.ping()
+			c.rekeyIfDue()
+			timer.Reset(timeDuration)
+			continue
+		}
+
+		if attempt == 0 {
+			lostAt = time.Now()
+		}
+		attempt++
+		c.onReconnecting(attempt)
+		err = c.connect(c.ctx)
+		if err == nil {
+			err = c.ping()
 		}
-		timer.Reset(timeDuration)
+		if err == nil {
+			err = c.markConnected(c.ctx)
+		}
+		if err == nil {
+			attempt = 0
+			select {
+			case c.chanReconnected <- struct{}{}:
+			default:
+			}
+			c.onConnected(c.GetID())
+			timer.Reset(timeDuration)
+			continue
+		}
+
+		delay, ok = c.options.ReconnectPolicy.NextDelay(attempt, time.Since(lostAt))
+		if ok == false {
+			c.giveUp(&ReconnectError{
+				Attempts: attempt,
+				Elapsed:  time.Since(lostAt),
+				Err:      err,
+			})
+			return
+		}
+		timer.Reset(delay)
 	}
 }
 
-func (c *client) connect() error {
+// giveUp reports the terminal error and closes the client
+func (c *client) giveUp(err error) {
+	c.wgLoop.Add(1)
+	go func() {
+		c.emitError(err)
+		c.wgLoop.Done()
+		c.Close()
+	}()
+}
+
+// getSocket returns socket of the current connection
+func (c *client) getSocket() socket.GEHSocket {
+	c.mtxConn.RLock()
+	defer c.mtxConn.RUnlock()
+	return c.socket
+}
+
+// getConn returns socket and security session of the current connection,
+// they always belong to the same handshake
+func (c *client) getConn() (socket.GEHSocket, *security.Session) {
+	c.mtxConn.RLock()
+	defer c.mtxConn.RUnlock()
+	return c.socket, c.session
+}
+
+func (c *client) connect(ctx context.Context) error {
 	var (
-		err    error
-		iv     []byte
-		data   []byte
-		ticket *pb.Ticket
+		err     error
+		iv      []byte
+		data    []byte
+		ticket  *pb.Ticket
+		session *security.Session
 	)
 
 	// Setup public key + shared key
-	err = c.setupSecurity(c.config.Host)
+	session, err = c.setupSecurity(ctx, c.hubURL)
 	if err != nil {
 		return err
 	}
 
 	// Register connection
-	ticket, err = c.register(c.config.Host)
+	ticket, err = c.register(ctx, c.hubURL, session)
 	if err != nil {
 		return err
 	}
@@ -195,9 +580,12 @@ func (c *client) connect() error {
 	if err != nil {
 		return err
 	}
-	iv, data, err = security.Encrypt(data)
+	iv, data, err = session.Encrypt(data)
+	if err != nil {
+		return err
+	}
 
-	id, err := security.EncryptRSA([]byte(ticket.ClientTicket.ConnID))
+	id, err := session.EncryptConnID(ticket.ClientTicket.ConnID)
 	if err != nil {
 		return err
 	}
@@ -214,131 +602,283 @@ func (c *client) connect() error {
 	}
 
 	// Create and activate socket
-	socket, err := socket.NewSocketClient(ticket.Address)
+	socket, err := socket.DialContext(ctx, ticket.Address, session, c.socketOptions)
 	if err != nil {
 		return err
 	}
-	err = socket.SendMessage(data)
+	err = socket.SendMessageContext(ctx, data)
 	if err != nil {
+		socket.Close()
 		return err
 	}
+	socket.SetSecretKey(ticket.SecretKey)
 
+	c.mtxConn.Lock()
 	if c.socket != nil {
 		c.socket.Close()
 	}
+	oldTicket := c.clientTicket
 	c.socket = socket
-	c.socket.SetSecretKey(ticket.SecretKey)
+	c.session = session
 	c.clientTicket = ticket.ClientTicket
+	c.resetRekeyCounters()
+	c.mtxConn.Unlock()
+
+	if oldTicket != nil && oldTicket.ConnID != ticket.ClientTicket.ConnID {
+		c.onConnIDChanged(oldTicket.ConnID, ticket.ClientTicket.ConnID)
+	}
 
 	return nil
 }
 
 func (c *client) RenameConnection(aliasName string) error {
+	return c.RenameConnectionContext(context.Background(), aliasName)
+}
+
+func (c *client) RenameConnectionContext(ctx context.Context, aliasName string) error {
 	var (
 		err  error
 		data []byte
 	)
 
+	c.mtxConn.RLock()
+	clientTicket := c.clientTicket
+	c.mtxConn.RUnlock()
+	if clientTicket == nil {
+		return ErrNotConnected
+	}
+
 	data, err = proto.Marshal(&pb.Client{
-		ID:        c.clientTicket.ConnID,
-		Token:     c.clientTicket.Token,
+		ID:        clientTicket.ConnID,
+		Token:     clientTicket.Token,
 		AliasName: aliasName,
 	})
 	if err != nil {
 		return err
 	}
 
-	err = c.sendMessage(pb.Letter_Rename, "", data, true)
+	err = c.sendMessage(ctx, pb.Letter_Rename, "", data, true)
 	if err != nil {
 		return err
 	}
-	c.clientInfo.AliasName = aliasName
+	// clientInfo is replaced, so it is not changed while it is marshalled
+	c.mtxConn.Lock()
+	c.clientInfo = &pb.Client{
+		ID:        c.clientInfo.ID,
+		Token:     c.clientInfo.Token,
+		AliasName: aliasName,
+	}
+	c.mtxConn.Unlock()
+	c.onRenamed(aliasName)
 	return nil
 }
 
 func (c *client) SendMessage(receiver string, data []byte, isEncrypted bool) error {
-	return c.sendMessage(pb.Letter_Single, receiver, data, isEncrypted)
+	return c.SendMessageContext(context.Background(), receiver, data, isEncrypted)
 }
 
-func (c *client) sendMessage(letterType pb.Letter_Type, receiver string, data []byte, isEncrypted bool) error {
-	buffer, err := c.buildMessage(&pb.Letter{
+func (c *client) SendMessageContext(ctx context.Context, receiver string, data []byte, isEncrypted bool) error {
+	return c.sendMessage(ctx, pb.Letter_Single, receiver, data, isEncrypted)
+}
+
+func (c *client) sendMessage(ctx context.Context, letterType pb.Letter_Type, receiver string, data []byte, isEncrypted bool) error {
+	return c.sendLetter(ctx, &pb.Letter{
 		Type:     letterType,
 		Receiver: receiver,
 		Data:     data,
 	}, isEncrypted)
+}
+
+func (c *client) sendLetter(ctx context.Context, letter *pb.Letter, isEncrypted bool) error {
+	_, err := c.sendOrQueueLetter(ctx, letter, isEncrypted)
+	return err
+}
+
+// sendOrQueueLetter is the same as sendLetter,
+// it also reports whether letter was pushed to the outbox instead of the socket
+func (c *client) sendOrQueueLetter(ctx context.Context, letter *pb.Letter, isEncrypted bool) (bool, error) {
+	c.mtxState.RLock()
+	if c.isClosed {
+		c.mtxState.RUnlock()
+		return false, ErrClosed
+	}
+	c.wgSend.Add(1)
+	c.mtxState.RUnlock()
+	defer c.wgSend.Done()
+
+	if c.getSocket() == nil {
+		return false, ErrNotConnected
+	}
+	c.sequenceLetter(letter)
+	c.signLetter(letter)
+
+	isQueued, err := c.queueLetter(letter, isEncrypted)
+	if isQueued {
+		return true, err
+	}
+
+	err = c.writeLetter(ctx, letter, isEncrypted)
+	if err != nil && ctx.Err() == nil && c.options.Outbox != nil && isQueueable(letter.Type) {
+		// The connection is broken, the letter is sent again after reconnecting
+		return true, c.pushOutbox(letter, isEncrypted)
+	}
+	return false, err
+}
+
+// writeLetter sends letter by the current socket
+func (c *client) writeLetter(ctx context.Context, letter *pb.Letter, isEncrypted bool) error {
+	socket, session := c.getConn()
+	buffer, err := c.buildMessage(session, letter, isEncrypted)
 	if err != nil {
 		return err
 	}
-	return c.socket.SendMessage(buffer)
+	err = socket.SendMessageContext(ctx, buffer)
+	if err == nil {
+		atomic.AddUint64(&c.writtenBytes, uint64(len(buffer)))
+	}
+	return err
 }
 
-func (c *client) setupSecurity(address string) error {
-	httpRes, err := http.Get(fmt.Sprintf("%s/public-key", c.config.Host))
+func (c *client) setupSecurity(ctx context.Context, address string) (*security.Session, error) {
+	path := "public-key"
+	if c.options.Handshake == security.HandshakeX25519 {
+		path = "ephemeral-key"
+	}
+
+	httpReq, err := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		fmt.Sprintf("%s/%s", address, path),
+		nil,
+	)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	httpReq.Header.Set("Version", c.announcedVersion())
+
+	httpRes, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
 	}
 
 	defer httpRes.Body.Close()
 
 	data, err := ioutil.ReadAll(httpRes.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var res socket.GEResponse
 	err = json.Unmarshal(data, &res)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if res.ReturnCode != 1 {
-		return errors.New(res.Data)
+		return nil, errors.New(res.Data)
 	}
 
 	buffer, err := base64.StdEncoding.DecodeString(res.Data)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = c.verifyKey(buffer, res.Signature)
+	if err != nil {
+		return nil, err
 	}
 
-	return security.Setup(buffer)
+	opts := security.SessionOptions{
+		Cipher:  c.options.Cipher,
+		KeyWrap: c.keyWrap,
+		Rand:    c.options.Rand,
+	}
+	if c.options.Handshake == security.HandshakeX25519 {
+		return security.NewSessionX25519(buffer, opts)
+	}
+	return security.NewSession(buffer, opts)
 }
 
-func (c *client) register(address string) (*pb.Ticket, error) {
-	var (
-		err     error
-		iv      []byte
-		data    []byte
-		body    []byte
-		httpReq *http.Request
-		httpRes *http.Response
-		res     socket.GEResponse
-	)
+// verifyKey checks the key served by GSCHub against the pinned settings of config
+func (c *client) verifyKey(key []byte, signature string) error {
+	if c.trust == nil {
+		return nil
+	}
 
-	// Build body
-	data, err = proto.Marshal(c.clientInfo)
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return &security.UntrustedKeyError{Reason: "invalid signature"}
+	}
+	err = c.trust.VerifySignature(key, sig)
+	if err != nil {
+		return err
+	}
+
+	if c.trust.HasPinnedKey() == false {
+		return nil
+	}
+	if c.options.Handshake == security.HandshakeX25519 {
+		// Ephemeral keys can't be pinned, only their signatures are verified
+		if c.trust.HasSigningKey() == false {
+			return &security.UntrustedKeyError{Reason: "pinned key requires RSA handshake or signing key"}
+		}
+		return nil
+	}
+	return c.trust.VerifyPublicKey(key)
+}
+
+// buildSharedKey wraps the shared key of session for GSCHub,
+// information of the client is encrypted with it to prove the key
+func (c *client) buildSharedKey(session *security.Session) (*pb.SharedKey, error) {
+	c.mtxConn.RLock()
+	clientInfo := c.clientInfo
+	c.mtxConn.RUnlock()
+	data, err := proto.Marshal(clientInfo)
 	if err != nil {
 		return nil, err
 	}
-	iv, data, err = security.Encrypt(data)
+	iv, data, err := session.Encrypt(data)
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := security.GetSharedKey()
+	key, err := session.GetSharedKey()
 	if err != nil {
 		return nil, err
 	}
 
-	sharedKey := pb.SharedKey{
+	return &pb.SharedKey{
 		Key: key,
 		Cipher: &pb.Cipher{
 			IV:   iv,
 			Data: data,
 		},
+		Suite:      pb.CipherSuite(session.GetCipher()),
+		ExchangeID: session.GetExchangeID(),
+	}, nil
+}
+
+func (c *client) register(ctx context.Context, address string, session *security.Session) (*pb.Ticket, error) {
+	var (
+		err     error
+		data    []byte
+		body    []byte
+		httpReq *http.Request
+		httpRes *http.Response
+		res     socket.GEResponse
+	)
+
+	// Build body
+	sharedKey, err := c.buildSharedKey(session)
+	if err != nil {
+		return nil, err
+	}
+	body, err = proto.Marshal(sharedKey)
+	if err != nil {
+		return nil, err
 	}
-	body, err = proto.Marshal(&sharedKey)
 
 	// Build request
-	httpReq, err = http.NewRequest(
+	httpReq, err = http.NewRequestWithContext(
+		ctx,
 		"POST",
 		fmt.Sprintf("%s/conn/register", address),
 		bytes.NewBuffer([]byte(base64.StdEncoding.EncodeToString(body))),
@@ -346,14 +886,11 @@ func (c *client) register(address string) (*pb.Ticket, error) {
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Version", Version)
+	httpReq.Header.Set("Version", protocolVersion(session))
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	client := http.Client{
-		Timeout: 5 * time.Second,
-	}
-	httpRes, err = client.Do(httpReq)
+	httpRes, err = c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -384,7 +921,7 @@ func (c *client) register(address string) (*pb.Ticket, error) {
 		return nil, err
 	}
 
-	data, err = security.Decrypt(cipher.IV, cipher.Data)
+	data, err = session.Decrypt(cipher.IV, cipher.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -395,10 +932,18 @@ func (c *client) register(address string) (*pb.Ticket, error) {
 }
 
 func (c *client) validateMessage(hmac, data []byte) bool {
-	realHMAC := calcHMAC(
-		c.socket.GetSecretKey(),
-		data,
-	)
+	socket := c.getSocket()
+	if checkHMAC(socket.GetSecretKey(), hmac, data) {
+		return true
+	}
+
+	// The message may be sent before the keys were rotated
+	prevKey := socket.GetPreviousSecretKey()
+	return prevKey != "" && checkHMAC(prevKey, hmac, data)
+}
+
+func checkHMAC(secretKey string, hmac, data []byte) bool {
+	realHMAC := calcHMAC(secretKey, data)
 
 	if len(realHMAC) != len(hmac) {
 		return false
@@ -413,10 +958,10 @@ func (c *client) validateMessage(hmac, data []byte) bool {
 }
 
 func (c *client) ping() error {
-	return c.sendMessage(pb.Letter_Ping, "", []byte("Ping"), true)
+	return c.sendMessage(c.ctx, pb.Letter_Ping, "", []byte("Ping"), true)
 }
 
-func (c *client) buildMessage(letter *pb.Letter, isEncrypted bool) ([]byte, error) {
+func (c *client) buildMessage(session *security.Session, letter *pb.Letter, isEncrypted bool) ([]byte, error) {
 	buffer, err := proto.Marshal(letter)
 	if err != nil {
 		return []byte{}, err
@@ -424,7 +969,7 @@ func (c *client) buildMessage(letter *pb.Letter, isEncrypted bool) ([]byte, erro
 
 	iv := []byte{}
 	if isEncrypted {
-		iv, buffer, err = security.Encrypt(buffer)
+		iv, buffer, err = session.Encrypt(buffer)
 		if err != nil {
 			return []byte{}, err
 		}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+// SignatureState is result of verifying signature of a received message
+type SignatureState int
+
+const (
+	// SignatureNotChecked means no SenderKeys is set
+	SignatureNotChecked SignatureState = iota
+	// SignatureValid means the message was signed by the key of the sender
+	SignatureValid
+	// SignatureMissing means the message is not signed
+	SignatureMissing
+	// SignatureUnknownSender means SenderKeys has no key of the sender
+	SignatureUnknownSender
+	// SignatureInvalid means the signature doesn't match the key of the sender
+	SignatureInvalid
+)
+
+func (s SignatureState) String() string {
+	switch s {
+	case SignatureValid:
+		return "valid"
+	case SignatureMissing:
+		return "missing"
+	case SignatureUnknownSender:
+		return "unknown sender"
+	case SignatureInvalid:
+		return "invalid"
+	}
+	return "not checked"
+}
+
+// SignaturePolicy decides what to do with messages whose signature is not valid
+type SignaturePolicy int
+
+const (
+	// SignatureFlag passes the messages to Listen, GEHMessage.Signature tells the state
+	SignatureFlag SignaturePolicy = iota
+	// SignatureReject drops the messages and sends SignatureError to the error channel of Listen
+	SignatureReject
+)
+
+// SenderKeys resolves Ed25519 public keys of senders, it is called by the receiving loop
+// so it should not block. ok is false if the key of sender is unknown.
+type SenderKeys interface {
+	Lookup(sender string) (key ed25519.PublicKey, ok bool)
+}
+
+// SignatureError is sent to the error channel of Listen when a message is rejected
+type SignatureError struct {
+	ID     string
+	Sender string
+	State  SignatureState
+}
+
+func (e *SignatureError) Error() string {
+	return "Signature of message from " + e.Sender + " is " + e.State.String()
+}
+
+// signatureContext separates signatures of letters from other uses of the signing key
+var signatureContext = []byte("gsc-go letter signature\x00")
+
+// isSignable reports whether data of letters of the type is signed
+func isSignable(letterType pb.Letter_Type) bool {
+	return letterType == pb.Letter_Single || letterType == pb.Letter_Group
+}
+
+// signedData is the canonical encoding of fields covered by signatures,
+// so the hub can't change the sequence or the receiver of a signed message
+func signedData(letterType pb.Letter_Type, receiver, id string, sequence uint64, data []byte) []byte {
+	buffer := make([]byte, 0, len(signatureContext)+24+len(receiver)+len(id)+len(data))
+	buffer = append(buffer, signatureContext...)
+	buffer = appendUint(buffer, uint64(letterType))
+	buffer = appendBytes(buffer, []byte(receiver))
+	buffer = appendBytes(buffer, []byte(id))
+	buffer = appendUint(buffer, sequence)
+	return appendBytes(buffer, data)
+}
+
+func appendUint(buffer []byte, value uint64) []byte {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], value)
+	return append(buffer, raw[:]...)
+}
+
+func appendBytes(buffer, value []byte) []byte {
+	buffer = appendUint(buffer, uint64(len(value)))
+	return append(buffer, value...)
+}
+
+// signLetter signs type, receiver, id, sequence and data of letter with the signing key of options
+func (c *client) signLetter(letter *pb.Letter) {
+	if c.options.SigningKey == nil || isSignable(letter.Type) == false {
+		return
+	}
+	data := signedData(letter.Type, letter.Receiver, letter.ID, letter.Sequence, letter.Data)
+	letter.Signature = ed25519.Sign(c.options.SigningKey, data)
+}
+
+// verifyReply checks signature of msg against the key of its sender,
+// the receiver of the letter is the group of msg or this connection
+func (c *client) verifyReply(msg *pb.Reply) SignatureState {
+	if c.options.SenderKeys == nil {
+		return SignatureNotChecked
+	}
+	if len(msg.Signature) == 0 {
+		return SignatureMissing
+	}
+	key, ok := c.options.SenderKeys.Lookup(msg.Sender)
+	if ok == false || len(key) != ed25519.PublicKeySize {
+		return SignatureUnknownSender
+	}
+	receiver := msg.Group
+	if msg.Type != pb.Letter_Group {
+		receiver = c.GetID()
+	}
+	data := signedData(msg.Type, receiver, msg.ID, msg.Sequence, msg.Data)
+	if ed25519.Verify(key, data, msg.Signature) == false {
+		return SignatureInvalid
+	}
+	return SignatureValid
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+type senderKeys map[string]ed25519.PublicKey
+
+func (k senderKeys) Lookup(sender string) (ed25519.PublicKey, bool) {
+	key, ok := k[sender]
+	return key, ok
+}
+
+// newKey returns a deterministic Ed25519 key pair, seed only fills the first byte
+func newKey(seed byte) (ed25519.PublicKey, ed25519.PrivateKey) {
+	raw := make([]byte, ed25519.SeedSize)
+	raw[0] = seed
+	key := ed25519.NewKeyFromSeed(raw)
+	return key.Public().(ed25519.PublicKey), key
+}
+
+// replyOf is the reply the hub delivers to the receiver of letter
+func replyOf(letter *pb.Letter, sender string) *pb.Reply {
+	reply := &pb.Reply{
+		Sender:    sender,
+		Data:      letter.Data,
+		Type:      letter.Type,
+		ID:        letter.ID,
+		Signature: letter.Signature,
+		Sequence:  letter.Sequence,
+	}
+	if letter.Type == pb.Letter_Group {
+		reply.Group = letter.Receiver
+	}
+	return reply
+}
+
+func TestSignature(t *testing.T) {
+	publicKey, privateKey := newKey(1)
+	otherKey, _ := newKey(2)
+	sender := newClient(&Options{SigningKey: privateKey})
+	receiver := newClient(&Options{SenderKeys: senderKeys{
+		"sender": publicKey,
+		"other":  otherKey,
+	}})
+	letterTypes := []struct {
+		letterType pb.Letter_Type
+		receiver   string
+	}{
+		{pb.Letter_Single, "receiver"},
+		{pb.Letter_Group, "group"},
+	}
+	// tamper changes the letter after it is signed, like the hub could
+	cases := []struct {
+		name   string
+		sender string
+		tamper func(letter *pb.Letter)
+		want   SignatureState
+	}{
+		{"valid", "sender", func(letter *pb.Letter) {}, SignatureValid},
+		{"receiver", "sender", func(letter *pb.Letter) { letter.Receiver = "another" }, SignatureInvalid},
+		{"type", "sender", func(letter *pb.Letter) {
+			if letter.Type == pb.Letter_Group {
+				letter.Type = pb.Letter_Single
+			} else {
+				letter.Type = pb.Letter_Group
+			}
+		}, SignatureInvalid},
+		{"sequence", "sender", func(letter *pb.Letter) { letter.Sequence++ }, SignatureInvalid},
+		{"data", "sender", func(letter *pb.Letter) { letter.Data[0] ^= 1 }, SignatureInvalid},
+		{"id", "sender", func(letter *pb.Letter) { letter.ID = "another id" }, SignatureInvalid},
+		{"missing", "sender", func(letter *pb.Letter) { letter.Signature = nil }, SignatureMissing},
+		{"unknown sender", "stranger", func(letter *pb.Letter) {}, SignatureUnknownSender},
+		{"other sender", "other", func(letter *pb.Letter) {}, SignatureInvalid},
+	}
+	for _, letterType := range letterTypes {
+		for _, tc := range cases {
+			letter := &pb.Letter{
+				Type:     letterType.letterType,
+				Receiver: letterType.receiver,
+				ID:       "id",
+				Sequence: 7,
+				Data:     []byte("data"),
+			}
+			sender.signLetter(letter)
+			if len(letter.Signature) != ed25519.SignatureSize {
+				t.Fatalf("%s: signLetter() left no signature", letterType.letterType)
+			}
+
+			tc.tamper(letter)
+			// The hub delivers single letters to the connection named by their receiver
+			receiver.clientTicket = &pb.ClientTicket{ConnID: letter.Receiver}
+			got := receiver.verifyReply(replyOf(letter, tc.sender))
+			if got != tc.want {
+				t.Errorf("%s %s: verifyReply() = %s, want %s", letterType.letterType, tc.name, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestSignatureNotChecked(t *testing.T) {
+	// Nothing is signed without SigningKey and nothing is checked without SenderKeys
+	c := newClient(&Options{})
+	letter := &pb.Letter{Type: pb.Letter_Single, Receiver: "receiver", Data: []byte("data")}
+	c.signLetter(letter)
+	if letter.Signature != nil {
+		t.Fatalf("signLetter() without SigningKey = %x, want nil", letter.Signature)
+	}
+	got := c.verifyReply(replyOf(letter, "sender"))
+	if got != SignatureNotChecked {
+		t.Fatalf("verifyReply() = %s, want %s", got, SignatureNotChecked)
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gecosys/gsc-go/config"
+	"github.com/gecosys/gsc-go/socket"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// unixPrefix is prefix of hosts which are Unix domain sockets, e.g. unix:///var/run/gsc-hub.sock
+const unixPrefix = socket.SchemeUnix + "://"
+
+// unixHubURL is base URL of requests sent through Unix domain sockets
+const unixHubURL = "http://localhost"
+
+// setupTransport applies TLS of options or conf to the HTTP handshake and the socket,
+// the HTTP handshake goes through the Unix domain socket if the host is unix://path
+func (c *client) setupTransport(conf *config.Config) error {
+	tlsConfig := c.options.TLSConfig
+	if tlsConfig == nil && conf.TLS != nil {
+		var err error
+		tlsConfig, err = conf.TLS.ClientConfig()
+		if err != nil {
+			return err
+		}
+	}
+
+	proxy, err := c.proxyFunc(conf)
+	if err != nil {
+		return err
+	}
+
+	c.socketOptions = &socket.Options{
+		TLSConfig:    tlsConfig,
+		Proxy:        proxy,
+		MaxFrameSize: c.options.MaxFrameSize,
+		FramePolicy:  c.options.FramePolicy,
+	}
+	c.hubURL = conf.Host
+
+	isUnix := strings.HasPrefix(conf.Host, unixPrefix)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+	if isUnix {
+		path := strings.TrimPrefix(conf.Host, unixPrefix)
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		c.hubURL = unixHubURL
+	}
+	c.httpClient.Transport = transport
+	return nil
+}
+
+// proxyFunc returns Proxy of options, or the proxy of conf which falls back to the environment
+func (c *client) proxyFunc(conf *config.Config) (func(*url.URL) (*url.URL, error), error) {
+	if c.options.Proxy != nil {
+		return c.options.Proxy, nil
+	}
+
+	proxyConfig := httpproxy.FromEnvironment()
+	if conf.Proxy == "" {
+		return proxyConfig.ProxyFunc(), nil
+	}
+
+	proxyURL, err := url.Parse(conf.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	// httpproxy only decides which hosts are excluded by NO_PROXY,
+	// it doesn't accept every scheme of proxies
+	proxyConfig.HTTPProxy = "http://proxy"
+	proxyConfig.HTTPSProxy = "http://proxy"
+	isProxied := proxyConfig.ProxyFunc()
+	return func(target *url.URL) (*url.URL, error) {
+		output, err := isProxied(target)
+		if err != nil || output == nil {
+			return nil, err
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// cloneTLSConfig avoids sharing tls.Config of options with other users
+func cloneTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		return nil
+	}
+	return config.Clone()
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/gecosys/gsc-go/config"
+	security "github.com/gecosys/gsc-go/security"
+)
+
+// ErrInvalidKeyWrap is returned by OpenConn when keyWrap of the config is unknown
+var ErrInvalidKeyWrap = errors.New("Invalid key wrap in config")
+
+// announcedVersion is the newest version the client supports by its options,
+// it is sent to GSCHub when requesting the public key
+func (c *client) announcedVersion() string {
+	if c.keyWrap == security.KeyWrapLegacy && c.options.Handshake == security.HandshakeRSA {
+		return Version
+	}
+	return VersionOAEP
+}
+
+// resolveKeyWrap chooses key wrap of new sessions by Options.KeyWrap, then by keyWrap of conf.
+// The version announced by GSCHub is not signed, so it can't be trusted to downgrade
+// to the legacy algorithm.
+func (c *client) resolveKeyWrap(conf *config.Config) (security.KeyWrap, error) {
+	if c.options.KeyWrap != security.KeyWrapAuto {
+		return c.options.KeyWrap, nil
+	}
+	switch conf.KeyWrap {
+	case "", "oaep":
+		return security.KeyWrapOAEP, nil
+	case "legacy":
+		return security.KeyWrapLegacy, nil
+	}
+	return security.KeyWrapAuto, ErrInvalidKeyWrap
+}
+
+// protocolVersion returns version sent to GSCHub when registering connection,
+// sessions using RSA-OAEP or X25519 handshake require VersionOAEP
+func protocolVersion(session *security.Session) string {
+	if session.GetKeyWrap() == security.KeyWrapOAEP || session.GetExchangeID() != "" {
+		return VersionOAEP
+	}
+	return Version
+}
@@ -7,33 +7,68 @@ import (
 	"sync"
 )
 
+// DefaultPath is the location of config file used by GetConfig
+const DefaultPath = "./gsc-services.json"
+
 var mtxConfig sync.Mutex
 var conf *Config
 
 type Config struct {
+	// Host is URL of GSCHub, e.g. https://host:port or unix:///path/of/socket
 	Host  string `json:"host"`
 	ID    string `json:"id"`
 	Token string `json:"token"`
+	// HubKeyFingerprint pins SHA-256 of the public key RSA of GSCHub in DER (PKIX) format,
+	// written in hex or base64
+	HubKeyFingerprint string `json:"hubKeyFingerprint"`
+	// HubKeyPEM pins the public key RSA of GSCHub in PEM format
+	HubKeyPEM string `json:"hubKeyPEM"`
+	// HubSigningKey is Ed25519 public key of GSCHub in base64,
+	// responses of key are required to be signed by it when it is set
+	HubSigningKey string `json:"hubSigningKey"`
+	// TLS enables TLS for connections to GSCHub, see TLSConfig
+	TLS *TLSConfig `json:"tls"`
+	// Proxy is URL of the proxy to GSCHub: http://, https://, socks5:// or socks5h://,
+	// user and password of the URL are used to authenticate.
+	// If it is empty, HTTP_PROXY, HTTPS_PROXY and NO_PROXY of the environment are used.
+	Proxy string `json:"proxy"`
+	// KeyWrap is algorithm encrypting the shared key with the public key of GSCHub:
+	// "oaep" (default) or "legacy" for hubs older than 3.0.0.
+	// client.Options.KeyWrap takes precedence when it is set.
+	KeyWrap string `json:"keyWrap"`
 }
 
+// GetConfig returns shared config loaded from DefaultPath
 func GetConfig() (*Config, error) {
 	if conf != nil {
 		return conf, nil
 	}
 
-	var (
-		err  error
-		file *os.File
-		data []byte
-	)
-
 	mtxConfig.Lock()
 	defer mtxConfig.Unlock()
 	if conf != nil {
 		return conf, nil
 	}
 
-	file, err = os.Open("./gsc-services.json")
+	c, err := Load(DefaultPath)
+	if err != nil {
+		return nil, err
+	}
+	conf = c
+	return conf, nil
+}
+
+// Load reads config from the file at path.
+// Every call returns a new Config, so it can be used to set up
+// several clients with different identities.
+func Load(path string) (*Config, error) {
+	var (
+		err  error
+		file *os.File
+		data []byte
+	)
+
+	file, err = os.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -44,10 +79,10 @@ func GetConfig() (*Config, error) {
 		return nil, err
 	}
 
-	conf = new(Config)
-	err = json.Unmarshal(data, conf)
+	c := new(Config)
+	err = json.Unmarshal(data, c)
 	if err != nil {
-		conf = nil
+		return nil, err
 	}
-	return conf, err
+	return c, nil
 }
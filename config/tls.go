@@ -0,0 +1,64 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// TLSConfig configures TLS of the HTTP handshake (https host) and of the socket connecting to GSCHub
+type TLSConfig struct {
+	// CAFile is PEM file of root CAs verifying GSCHub (default: CAs of the system)
+	CAFile string `json:"caFile"`
+	// CertFile and KeyFile are PEM files of the client certificate for mutual TLS
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// ServerName verifies certificates of GSCHub (default: host of the address)
+	ServerName string `json:"serverName"`
+	// MinVersion is the minimum version of TLS: "1.0", "1.1", "1.2" or "1.3" (default: "1.2")
+	MinVersion string `json:"minVersion"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ClientConfig loads files of the config and creates tls.Config for clients
+func (t *TLSConfig) ClientConfig() (*tls.Config, error) {
+	output := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if ok == false {
+			return nil, errors.New("Invalid minimum version of TLS")
+		}
+		output.MinVersion = version
+	}
+
+	if t.CAFile != "" {
+		data, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		output.RootCAs = x509.NewCertPool()
+		if output.RootCAs.AppendCertsFromPEM(data) == false {
+			return nil, errors.New("Cannot load root CAs")
+		}
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		output.Certificates = []tls.Certificate{cert}
+	}
+	return output, nil
+}
@@ -0,0 +1,354 @@
+package e2e
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gecosys/gsc-go/client"
+	"github.com/gecosys/gsc-go/internal/demux"
+	"github.com/gecosys/gsc-go/internal/randid"
+	pb "github.com/gecosys/gsc-go/message"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// KeySize is the size of public and private keys of peers
+const KeySize = 32
+
+// DefaultTimeout is the limit of time to wait for the key of a receiver
+// when the context of SendMessage has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// magic marks data of E2E messages among other messages of the client
+var magic = []byte{0x00, 'E', '2', 'E'}
+
+var (
+	// ErrClosed is returned when the peer or its client is closed
+	ErrClosed = errors.New("E2E peer is closed")
+	// ErrInvalidKey is returned when the key of a receiver has wrong size
+	ErrInvalidKey = errors.New("Invalid key of receiver")
+	// ErrCannotOpen is sent to the error channel when a sealed message
+	// is not for this peer or it was modified
+	ErrCannotOpen = errors.New("Cannot open sealed message")
+)
+
+// KeyChangedError is sent to the error channel when a key received through the hub
+// is different from the known key of the sender, the known key is kept
+type KeyChangedError struct {
+	Sender string
+}
+
+func (e *KeyChangedError) Error() string {
+	return "Key of " + e.Sender + " changed, the known key is kept"
+}
+
+// Directory resolves public keys of receivers out of band,
+// e.g. from a service the peers trust more than the hub
+type Directory interface {
+	Lookup(ctx context.Context, receiver string) ([]byte, error)
+}
+
+// Options configures a Peer
+type Options struct {
+	// PrivateKey of the peer, a new key is generated if it is nil
+	PrivateKey []byte
+	// Directory resolves keys of receivers, keys are requested through the hub if it is nil.
+	// Keys requested or published through the hub are trusted on first use and never
+	// replace a known key, so they are only as trusted as the hub was at that time.
+	Directory Directory
+	// Timeout is used when the context of SendMessage has no deadline (default: DefaultTimeout)
+	Timeout time.Duration
+	// DisableEncryption sends sealed messages without encryption between client and hub
+	DisableEncryption bool
+	// Rand is the source of randomness (default: crypto/rand.Reader)
+	Rand io.Reader
+}
+
+// Peer seals messages for their receivers, so the hub only routes opaque bytes.
+// It is the only reader of its client, see GEHClient.Listen.
+type Peer struct {
+	client      client.GEHClient
+	options     Options
+	publicKey   *[KeySize]byte
+	privateKey  *[KeySize]byte
+	demux       *demux.Demux
+	mtxKeys     sync.RWMutex
+	keys        map[string]*[KeySize]byte
+	mtxRequests sync.Mutex
+	requests    map[string]*keyRequest
+}
+
+// keyRequest waits for the key of receiver requested through the hub
+type keyRequest struct {
+	receiver string
+	chanKey  chan []byte
+}
+
+// New creates a Peer working over c, c should be opened by the caller
+func New(c client.GEHClient, opts *Options) (*Peer, error) {
+	p := &Peer{
+		client:   c,
+		keys:     make(map[string]*[KeySize]byte),
+		requests: make(map[string]*keyRequest),
+	}
+	if opts != nil {
+		p.options = *opts
+	}
+	if p.options.Timeout <= 0 {
+		p.options.Timeout = DefaultTimeout
+	}
+	if p.options.Rand == nil {
+		p.options.Rand = rand.Reader
+	}
+
+	if p.options.PrivateKey == nil {
+		publicKey, privateKey, err := box.GenerateKey(p.options.Rand)
+		if err != nil {
+			return nil, err
+		}
+		p.publicKey, p.privateKey = publicKey, privateKey
+	} else {
+		privateKey, err := toKey(p.options.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := curve25519.X25519(privateKey[:], curve25519.Basepoint)
+		if err != nil {
+			return nil, err
+		}
+		p.privateKey = privateKey
+		p.publicKey, _ = toKey(publicKey)
+	}
+	p.demux = demux.New(magic, p.dispatch)
+	go p.demux.Run(c.Listen())
+	return p, nil
+}
+
+// PublicKey returns the public key of the peer, it can be published out of band
+func (p *Peer) PublicKey() []byte {
+	return append([]byte(nil), p.publicKey[:]...)
+}
+
+// AddKey sets the public key of receiver, it replaces the previous key of receiver.
+// Keys received through the hub never replace it.
+func (p *Peer) AddKey(receiver string, key []byte) error {
+	k, err := toKey(key)
+	if err != nil {
+		return err
+	}
+	p.mtxKeys.Lock()
+	defer p.mtxKeys.Unlock()
+	p.keys[receiver] = k
+	return nil
+}
+
+// Listen returns channels of opened messages, messages which are not sealed and errors.
+// They are only fed after the first call, then they must be drained.
+func (p *Peer) Listen() (chan *client.GEHMessage, chan error) {
+	return p.demux.Listen()
+}
+
+// SendMessage seals data for receiver and sends it
+func (p *Peer) SendMessage(receiver string, data []byte) error {
+	return p.SendMessageContext(context.Background(), receiver, data)
+}
+
+// SendMessageContext seals data for receiver and sends it,
+// the key of receiver is resolved first if it is unknown
+func (p *Peer) SendMessageContext(ctx context.Context, receiver string, data []byte) error {
+	key, err := p.resolveKey(ctx, receiver)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := box.SealAnonymous(nil, data, key, p.options.Rand)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, receiver, &pb.E2E{
+		Kind: pb.E2E_Sealed,
+		Data: sealed,
+	})
+}
+
+// Publish sends the public key of the peer to receiver, so receiver doesn't
+// have to request it. Receiver only accepts it if it doesn't know a key of the peer.
+func (p *Peer) Publish(ctx context.Context, receiver string) error {
+	return p.send(ctx, receiver, &pb.E2E{
+		Kind: pb.E2E_Key,
+		Key:  p.PublicKey(),
+	})
+}
+
+func (p *Peer) resolveKey(ctx context.Context, receiver string) (*[KeySize]byte, error) {
+	p.mtxKeys.RLock()
+	key, ok := p.keys[receiver]
+	p.mtxKeys.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if _, ok := ctx.Deadline(); ok == false {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.Timeout)
+		defer cancel()
+	}
+
+	var (
+		err error
+		raw []byte
+	)
+	if p.options.Directory != nil {
+		raw, err = p.options.Directory.Lookup(ctx, receiver)
+	} else {
+		raw, err = p.requestKey(ctx, receiver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err = toKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return p.trustKey(receiver, key)
+}
+
+// trustKey stores key of sender if its key is unknown,
+// it returns KeyChangedError if the known key is different
+func (p *Peer) trustKey(sender string, key *[KeySize]byte) (*[KeySize]byte, error) {
+	p.mtxKeys.Lock()
+	defer p.mtxKeys.Unlock()
+	known, ok := p.keys[sender]
+	if ok == false {
+		p.keys[sender] = key
+		return key, nil
+	}
+	if *known != *key {
+		return nil, &KeyChangedError{Sender: sender}
+	}
+	return known, nil
+}
+
+// requestKey asks receiver for its public key through the hub
+func (p *Peer) requestKey(ctx context.Context, receiver string) ([]byte, error) {
+	id, err := randid.New()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &keyRequest{
+		receiver: receiver,
+		chanKey:  make(chan []byte, 1),
+	}
+	p.mtxRequests.Lock()
+	p.requests[id] = req
+	p.mtxRequests.Unlock()
+	defer func() {
+		p.mtxRequests.Lock()
+		delete(p.requests, id)
+		p.mtxRequests.Unlock()
+	}()
+
+	err = p.send(ctx, receiver, &pb.E2E{
+		Kind: pb.E2E_KeyRequest,
+		ID:   id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case key := <-req.chanKey:
+		return key, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.demux.Context().Done():
+		return nil, ErrClosed
+	}
+}
+
+func (p *Peer) send(ctx context.Context, receiver string, msg *pb.E2E) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	buffer := make([]byte, 0, len(magic)+len(data))
+	buffer = append(buffer, magic...)
+	buffer = append(buffer, data...)
+	return p.client.SendMessageContext(ctx, receiver, buffer, p.options.DisableEncryption == false)
+}
+
+func (p *Peer) dispatch(msg *client.GEHMessage, payload []byte) {
+	e2e := new(pb.E2E)
+	err := proto.Unmarshal(payload, e2e)
+	if err != nil {
+		p.demux.ForwardError(err)
+		return
+	}
+
+	switch e2e.Kind {
+	case pb.E2E_Sealed:
+		data, ok := box.OpenAnonymous(nil, e2e.Data, p.publicKey, p.privateKey)
+		if ok == false {
+			p.demux.ForwardError(ErrCannotOpen)
+			return
+		}
+		opened := *msg
+		opened.Data = data
+		p.demux.ForwardMessage(&opened)
+	case pb.E2E_KeyRequest:
+		ctx := p.demux.Context()
+		p.demux.Go(func() {
+			err := p.send(ctx, msg.Sender, &pb.E2E{
+				Kind: pb.E2E_Key,
+				ID:   e2e.ID,
+				Key:  p.PublicKey(),
+			})
+			if err != nil && ctx.Err() == nil {
+				p.demux.ForwardError(err)
+			}
+		})
+	case pb.E2E_Key:
+		p.mtxRequests.Lock()
+		req, ok := p.requests[e2e.ID]
+		p.mtxRequests.Unlock()
+		if ok {
+			// Only the receiver answers with its key
+			if req.receiver == msg.Sender {
+				select {
+				case req.chanKey <- e2e.Key:
+				default:
+				}
+			}
+			return
+		}
+
+		// Published keys are only accepted when keys are not resolved by a directory
+		if p.options.Directory != nil {
+			return
+		}
+		key, err := toKey(e2e.Key)
+		if err == nil {
+			_, err = p.trustKey(msg.Sender, key)
+		}
+		if err != nil {
+			p.demux.ForwardError(err)
+		}
+	}
+}
+
+func toKey(data []byte) (*[KeySize]byte, error) {
+	if len(data) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	key := new([KeySize]byte)
+	copy(key[:], data)
+	return key, nil
+}
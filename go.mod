@@ -2,4 +2,8 @@ module github.com/gecosys/gsc-go
 
 go 1.13
 
-require github.com/golang/protobuf v1.3.2
+require (
+	github.com/golang/protobuf v1.3.2
+	golang.org/x/crypto v0.9.0
+	golang.org/x/net v0.10.0
+)
@@ -0,0 +1,117 @@
+package demux
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gecosys/gsc-go/client"
+)
+
+// Handler receives messages whose data starts with the prefix,
+// payload is data of the message without the prefix
+type Handler func(msg *client.GEHMessage, payload []byte)
+
+// Demux splits messages of a GEHClient between a peer built over it (rpc, e2e)
+// and the user of the peer. The peer consumes channels of GEHClient.Listen,
+// so the client must not be read directly or shared with another peer.
+// Messages starting with its prefix are passed to its handler, other messages
+// and errors are passed to the channels of Listen once it is called,
+// and dropped before, so the handler never waits for them.
+type Demux struct {
+	prefix      []byte
+	handler     Handler
+	ctx         context.Context
+	cancel      context.CancelFunc
+	chanMessage chan *client.GEHMessage
+	chanError   chan error
+	isListening int32
+	wgServe     sync.WaitGroup
+}
+
+// New creates a Demux, Run starts reading messages
+func New(prefix []byte, handler Handler) *Demux {
+	d := &Demux{
+		prefix:      prefix,
+		handler:     handler,
+		chanMessage: make(chan *client.GEHMessage),
+		chanError:   make(chan error),
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// Context is done when the channels of the client are closed
+func (d *Demux) Context() context.Context {
+	return d.ctx
+}
+
+// Listen returns channels of messages and errors which are not for the handler,
+// they are closed when the client is closed.
+// After the first call, the channels must be drained or the handler stops receiving messages.
+func (d *Demux) Listen() (chan *client.GEHMessage, chan error) {
+	atomic.StoreInt32(&d.isListening, 1)
+	return d.chanMessage, d.chanError
+}
+
+// Go runs fn in a goroutine, the channels of Listen are closed after fn returns
+func (d *Demux) Go(fn func()) {
+	d.wgServe.Add(1)
+	go func() {
+		defer d.wgServe.Done()
+		fn()
+	}()
+}
+
+// Run dispatches messages of the client until its channels are closed
+func (d *Demux) Run(chanMessage chan *client.GEHMessage, chanError chan error) {
+	defer func() {
+		d.cancel()
+		d.wgServe.Wait()
+		close(d.chanMessage)
+		close(d.chanError)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-chanMessage:
+			if ok == false {
+				return
+			}
+			if bytes.HasPrefix(msg.Data, d.prefix) == false {
+				d.ForwardMessage(msg)
+				continue
+			}
+			d.handler(msg, msg.Data[len(d.prefix):])
+		case err, ok := <-chanError:
+			if ok == false {
+				return
+			}
+			d.ForwardError(err)
+		}
+	}
+}
+
+// ForwardMessage passes msg to the channel of Listen,
+// it is dropped when nobody listens
+func (d *Demux) ForwardMessage(msg *client.GEHMessage) {
+	if atomic.LoadInt32(&d.isListening) == 0 {
+		return
+	}
+	select {
+	case d.chanMessage <- msg:
+	case <-d.ctx.Done():
+	}
+}
+
+// ForwardError passes err to the channel of Listen, like ForwardMessage
+func (d *Demux) ForwardError(err error) {
+	if atomic.LoadInt32(&d.isListening) == 0 {
+		return
+	}
+	select {
+	case d.chanError <- err:
+	case <-d.ctx.Done():
+	}
+}
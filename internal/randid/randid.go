@@ -0,0 +1,17 @@
+package randid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// New returns a random id of 16 bytes in hex, it is used to match
+// answers with their requests and acknowledgements with their messages
+func New() (string, error) {
+	buffer := make([]byte, 16)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
@@ -20,13 +20,46 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type CipherSuite int32
+
+const (
+	CipherSuite_AES_CBC           CipherSuite = 0
+	CipherSuite_AES_GCM           CipherSuite = 1
+	CipherSuite_CHACHA20_POLY1305 CipherSuite = 2
+)
+
+var CipherSuite_name = map[int32]string{
+	0: "AES_CBC",
+	1: "AES_GCM",
+	2: "CHACHA20_POLY1305",
+}
+
+var CipherSuite_value = map[string]int32{
+	"AES_CBC":           0,
+	"AES_GCM":           1,
+	"CHACHA20_POLY1305": 2,
+}
+
+func (x CipherSuite) String() string {
+	return proto.EnumName(CipherSuite_name, int32(x))
+}
+
+func (CipherSuite) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{0}
+}
+
 type Letter_Type int32
 
 const (
-	Letter_Single Letter_Type = 0
-	Letter_Group  Letter_Type = 1
-	Letter_Ping   Letter_Type = 2
-	Letter_Rename Letter_Type = 3
+	Letter_Single     Letter_Type = 0
+	Letter_Group      Letter_Type = 1
+	Letter_Ping       Letter_Type = 2
+	Letter_Rename     Letter_Type = 3
+	Letter_JoinGroup  Letter_Type = 4
+	Letter_LeaveGroup Letter_Type = 5
+	Letter_ListGroups Letter_Type = 6
+	Letter_Ack        Letter_Type = 7
+	Letter_Rekey      Letter_Type = 8
 )
 
 var Letter_Type_name = map[int32]string{
@@ -34,13 +67,23 @@ var Letter_Type_name = map[int32]string{
 	1: "Group",
 	2: "Ping",
 	3: "Rename",
+	4: "JoinGroup",
+	5: "LeaveGroup",
+	6: "ListGroups",
+	7: "Ack",
+	8: "Rekey",
 }
 
 var Letter_Type_value = map[string]int32{
-	"Single": 0,
-	"Group":  1,
-	"Ping":   2,
-	"Rename": 3,
+	"Single":     0,
+	"Group":      1,
+	"Ping":       2,
+	"Rename":     3,
+	"JoinGroup":  4,
+	"LeaveGroup": 5,
+	"ListGroups": 6,
+	"Ack":        7,
+	"Rekey":      8,
 }
 
 func (x Letter_Type) String() string {
@@ -48,7 +91,88 @@ func (x Letter_Type) String() string {
 }
 
 func (Letter_Type) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{7, 0}
+	return fileDescriptor_ebceca9e8703e37f, []int{8, 0}
+}
+
+type Letter_AckMode int32
+
+const (
+	Letter_AckNone     Letter_AckMode = 0
+	Letter_AckHub      Letter_AckMode = 1
+	Letter_AckReceiver Letter_AckMode = 2
+)
+
+var Letter_AckMode_name = map[int32]string{
+	0: "AckNone",
+	1: "AckHub",
+	2: "AckReceiver",
+}
+
+var Letter_AckMode_value = map[string]int32{
+	"AckNone":     0,
+	"AckHub":      1,
+	"AckReceiver": 2,
+}
+
+func (x Letter_AckMode) String() string {
+	return proto.EnumName(Letter_AckMode_name, int32(x))
+}
+
+func (Letter_AckMode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{8, 1}
+}
+
+type RPC_Kind int32
+
+const (
+	RPC_Request  RPC_Kind = 0
+	RPC_Response RPC_Kind = 1
+)
+
+var RPC_Kind_name = map[int32]string{
+	0: "Request",
+	1: "Response",
+}
+
+var RPC_Kind_value = map[string]int32{
+	"Request":  0,
+	"Response": 1,
+}
+
+func (x RPC_Kind) String() string {
+	return proto.EnumName(RPC_Kind_name, int32(x))
+}
+
+func (RPC_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{11, 0}
+}
+
+type E2E_Kind int32
+
+const (
+	E2E_Sealed     E2E_Kind = 0
+	E2E_KeyRequest E2E_Kind = 1
+	E2E_Key        E2E_Kind = 2
+)
+
+var E2E_Kind_name = map[int32]string{
+	0: "Sealed",
+	1: "KeyRequest",
+	2: "Key",
+}
+
+var E2E_Kind_value = map[string]int32{
+	"Sealed":     0,
+	"KeyRequest": 1,
+	"Key":        2,
+}
+
+func (x E2E_Kind) String() string {
+	return proto.EnumName(E2E_Kind_name, int32(x))
+}
+
+func (E2E_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{12, 0}
 }
 
 type PublicKey struct {
@@ -99,11 +223,13 @@ func (m *PublicKey) GetN() string {
 }
 
 type SharedKey struct {
-	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Cipher               *Cipher  `protobuf:"bytes,2,opt,name=cipher,proto3" json:"cipher,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key                  []byte      `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Cipher               *Cipher     `protobuf:"bytes,2,opt,name=cipher,proto3" json:"cipher,omitempty"`
+	Suite                CipherSuite `protobuf:"varint,3,opt,name=suite,proto3,enum=gschub.CipherSuite" json:"suite,omitempty"`
+	ExchangeID           string      `protobuf:"bytes,4,opt,name=exchangeID,proto3" json:"exchangeID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *SharedKey) Reset()         { *m = SharedKey{} }
@@ -145,6 +271,67 @@ func (m *SharedKey) GetCipher() *Cipher {
 	return nil
 }
 
+func (m *SharedKey) GetSuite() CipherSuite {
+	if m != nil {
+		return m.Suite
+	}
+	return CipherSuite_AES_CBC
+}
+
+func (m *SharedKey) GetExchangeID() string {
+	if m != nil {
+		return m.ExchangeID
+	}
+	return ""
+}
+
+type EphemeralKey struct {
+	ID                   string   `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EphemeralKey) Reset()         { *m = EphemeralKey{} }
+func (m *EphemeralKey) String() string { return proto.CompactTextString(m) }
+func (*EphemeralKey) ProtoMessage()    {}
+func (*EphemeralKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{2}
+}
+
+func (m *EphemeralKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EphemeralKey.Unmarshal(m, b)
+}
+func (m *EphemeralKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EphemeralKey.Marshal(b, m, deterministic)
+}
+func (m *EphemeralKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EphemeralKey.Merge(m, src)
+}
+func (m *EphemeralKey) XXX_Size() int {
+	return xxx_messageInfo_EphemeralKey.Size(m)
+}
+func (m *EphemeralKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_EphemeralKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EphemeralKey proto.InternalMessageInfo
+
+func (m *EphemeralKey) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *EphemeralKey) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
 type Cipher struct {
 	IV                   []byte   `protobuf:"bytes,1,opt,name=IV,proto3" json:"IV,omitempty"`
 	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
@@ -157,7 +344,7 @@ func (m *Cipher) Reset()         { *m = Cipher{} }
 func (m *Cipher) String() string { return proto.CompactTextString(m) }
 func (*Cipher) ProtoMessage()    {}
 func (*Cipher) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{2}
+	return fileDescriptor_ebceca9e8703e37f, []int{3}
 }
 
 func (m *Cipher) XXX_Unmarshal(b []byte) error {
@@ -204,7 +391,7 @@ func (m *CipherTicket) Reset()         { *m = CipherTicket{} }
 func (m *CipherTicket) String() string { return proto.CompactTextString(m) }
 func (*CipherTicket) ProtoMessage()    {}
 func (*CipherTicket) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{3}
+	return fileDescriptor_ebceca9e8703e37f, []int{4}
 }
 
 func (m *CipherTicket) XXX_Unmarshal(b []byte) error {
@@ -252,7 +439,7 @@ func (m *Client) Reset()         { *m = Client{} }
 func (m *Client) String() string { return proto.CompactTextString(m) }
 func (*Client) ProtoMessage()    {}
 func (*Client) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{4}
+	return fileDescriptor_ebceca9e8703e37f, []int{5}
 }
 
 func (m *Client) XXX_Unmarshal(b []byte) error {
@@ -307,7 +494,7 @@ func (m *Ticket) Reset()         { *m = Ticket{} }
 func (m *Ticket) String() string { return proto.CompactTextString(m) }
 func (*Ticket) ProtoMessage()    {}
 func (*Ticket) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{5}
+	return fileDescriptor_ebceca9e8703e37f, []int{6}
 }
 
 func (m *Ticket) XXX_Unmarshal(b []byte) error {
@@ -361,7 +548,7 @@ func (m *ClientTicket) Reset()         { *m = ClientTicket{} }
 func (m *ClientTicket) String() string { return proto.CompactTextString(m) }
 func (*ClientTicket) ProtoMessage()    {}
 func (*ClientTicket) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{6}
+	return fileDescriptor_ebceca9e8703e37f, []int{7}
 }
 
 func (m *ClientTicket) XXX_Unmarshal(b []byte) error {
@@ -397,19 +584,23 @@ func (m *ClientTicket) GetToken() string {
 }
 
 type Letter struct {
-	Type                 Letter_Type `protobuf:"varint,1,opt,name=type,proto3,enum=gschub.Letter_Type" json:"type,omitempty"`
-	Receiver             string      `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
-	Data                 []byte      `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Type                 Letter_Type    `protobuf:"varint,1,opt,name=type,proto3,enum=gschub.Letter_Type" json:"type,omitempty"`
+	Receiver             string         `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Data                 []byte         `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	ID                   string         `protobuf:"bytes,4,opt,name=ID,proto3" json:"ID,omitempty"`
+	Ack                  Letter_AckMode `protobuf:"varint,5,opt,name=ack,proto3,enum=gschub.Letter_AckMode" json:"ack,omitempty"`
+	Signature            []byte         `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	Sequence             uint64         `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *Letter) Reset()         { *m = Letter{} }
 func (m *Letter) String() string { return proto.CompactTextString(m) }
 func (*Letter) ProtoMessage()    {}
 func (*Letter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{7}
+	return fileDescriptor_ebceca9e8703e37f, []int{8}
 }
 
 func (m *Letter) XXX_Unmarshal(b []byte) error {
@@ -451,21 +642,55 @@ func (m *Letter) GetData() []byte {
 	return nil
 }
 
+func (m *Letter) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *Letter) GetAck() Letter_AckMode {
+	if m != nil {
+		return m.Ack
+	}
+	return Letter_AckNone
+}
+
+func (m *Letter) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Letter) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
 type Reply struct {
-	Sender               string   `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
-	HMAC                 []byte   `protobuf:"bytes,2,opt,name=HMAC,proto3" json:"HMAC,omitempty"`
-	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
-	Timestamp            int32    `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Sender               string         `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	HMAC                 []byte         `protobuf:"bytes,2,opt,name=HMAC,proto3" json:"HMAC,omitempty"`
+	Data                 []byte         `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	Timestamp            int32          `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Type                 Letter_Type    `protobuf:"varint,5,opt,name=type,proto3,enum=gschub.Letter_Type" json:"type,omitempty"`
+	Group                string         `protobuf:"bytes,6,opt,name=group,proto3" json:"group,omitempty"`
+	ID                   string         `protobuf:"bytes,7,opt,name=ID,proto3" json:"ID,omitempty"`
+	Ack                  Letter_AckMode `protobuf:"varint,8,opt,name=ack,proto3,enum=gschub.Letter_AckMode" json:"ack,omitempty"`
+	Signature            []byte         `protobuf:"bytes,9,opt,name=signature,proto3" json:"signature,omitempty"`
+	Sequence             uint64         `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *Reply) Reset()         { *m = Reply{} }
 func (m *Reply) String() string { return proto.CompactTextString(m) }
 func (*Reply) ProtoMessage()    {}
 func (*Reply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ebceca9e8703e37f, []int{8}
+	return fileDescriptor_ebceca9e8703e37f, []int{9}
 }
 
 func (m *Reply) XXX_Unmarshal(b []byte) error {
@@ -514,10 +739,230 @@ func (m *Reply) GetTimestamp() int32 {
 	return 0
 }
 
+func (m *Reply) GetType() Letter_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Letter_Single
+}
+
+func (m *Reply) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *Reply) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *Reply) GetAck() Letter_AckMode {
+	if m != nil {
+		return m.Ack
+	}
+	return Letter_AckNone
+}
+
+func (m *Reply) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Reply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+type GroupList struct {
+	Groups               []string `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GroupList) Reset()         { *m = GroupList{} }
+func (m *GroupList) String() string { return proto.CompactTextString(m) }
+func (*GroupList) ProtoMessage()    {}
+func (*GroupList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{10}
+}
+
+func (m *GroupList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GroupList.Unmarshal(m, b)
+}
+func (m *GroupList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GroupList.Marshal(b, m, deterministic)
+}
+func (m *GroupList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GroupList.Merge(m, src)
+}
+func (m *GroupList) XXX_Size() int {
+	return xxx_messageInfo_GroupList.Size(m)
+}
+func (m *GroupList) XXX_DiscardUnknown() {
+	xxx_messageInfo_GroupList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GroupList proto.InternalMessageInfo
+
+func (m *GroupList) GetGroups() []string {
+	if m != nil {
+		return m.Groups
+	}
+	return nil
+}
+
+type RPC struct {
+	Kind                 RPC_Kind `protobuf:"varint,1,opt,name=kind,proto3,enum=gschub.RPC_Kind" json:"kind,omitempty"`
+	CorrelationID        string   `protobuf:"bytes,2,opt,name=correlationID,proto3" json:"correlationID,omitempty"`
+	Method               string   `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
+	Payload              []byte   `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RPC) Reset()         { *m = RPC{} }
+func (m *RPC) String() string { return proto.CompactTextString(m) }
+func (*RPC) ProtoMessage()    {}
+func (*RPC) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{11}
+}
+
+func (m *RPC) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RPC.Unmarshal(m, b)
+}
+func (m *RPC) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RPC.Marshal(b, m, deterministic)
+}
+func (m *RPC) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RPC.Merge(m, src)
+}
+func (m *RPC) XXX_Size() int {
+	return xxx_messageInfo_RPC.Size(m)
+}
+func (m *RPC) XXX_DiscardUnknown() {
+	xxx_messageInfo_RPC.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RPC proto.InternalMessageInfo
+
+func (m *RPC) GetKind() RPC_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return RPC_Request
+}
+
+func (m *RPC) GetCorrelationID() string {
+	if m != nil {
+		return m.CorrelationID
+	}
+	return ""
+}
+
+func (m *RPC) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *RPC) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *RPC) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type E2E struct {
+	Kind                 E2E_Kind `protobuf:"varint,1,opt,name=kind,proto3,enum=gschub.E2E_Kind" json:"kind,omitempty"`
+	ID                   string   `protobuf:"bytes,2,opt,name=ID,proto3" json:"ID,omitempty"`
+	Key                  []byte   `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *E2E) Reset()         { *m = E2E{} }
+func (m *E2E) String() string { return proto.CompactTextString(m) }
+func (*E2E) ProtoMessage()    {}
+func (*E2E) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ebceca9e8703e37f, []int{12}
+}
+
+func (m *E2E) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_E2E.Unmarshal(m, b)
+}
+func (m *E2E) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_E2E.Marshal(b, m, deterministic)
+}
+func (m *E2E) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_E2E.Merge(m, src)
+}
+func (m *E2E) XXX_Size() int {
+	return xxx_messageInfo_E2E.Size(m)
+}
+func (m *E2E) XXX_DiscardUnknown() {
+	xxx_messageInfo_E2E.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_E2E proto.InternalMessageInfo
+
+func (m *E2E) GetKind() E2E_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return E2E_Sealed
+}
+
+func (m *E2E) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *E2E) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *E2E) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterEnum("gschub.CipherSuite", CipherSuite_name, CipherSuite_value)
 	proto.RegisterEnum("gschub.Letter_Type", Letter_Type_name, Letter_Type_value)
+	proto.RegisterEnum("gschub.Letter_AckMode", Letter_AckMode_name, Letter_AckMode_value)
+	proto.RegisterEnum("gschub.RPC_Kind", RPC_Kind_name, RPC_Kind_value)
+	proto.RegisterEnum("gschub.E2E_Kind", E2E_Kind_name, E2E_Kind_value)
 	proto.RegisterType((*PublicKey)(nil), "gschub.PublicKey")
 	proto.RegisterType((*SharedKey)(nil), "gschub.SharedKey")
+	proto.RegisterType((*EphemeralKey)(nil), "gschub.EphemeralKey")
 	proto.RegisterType((*Cipher)(nil), "gschub.Cipher")
 	proto.RegisterType((*CipherTicket)(nil), "gschub.CipherTicket")
 	proto.RegisterType((*Client)(nil), "gschub.Client")
@@ -525,37 +970,67 @@ func init() {
 	proto.RegisterType((*ClientTicket)(nil), "gschub.ClientTicket")
 	proto.RegisterType((*Letter)(nil), "gschub.Letter")
 	proto.RegisterType((*Reply)(nil), "gschub.Reply")
+	proto.RegisterType((*GroupList)(nil), "gschub.GroupList")
+	proto.RegisterType((*RPC)(nil), "gschub.RPC")
+	proto.RegisterType((*E2E)(nil), "gschub.E2E")
 }
 
 func init() { proto.RegisterFile("message/message.proto", fileDescriptor_ebceca9e8703e37f) }
 
 var fileDescriptor_ebceca9e8703e37f = []byte{
-	// 431 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0x4b, 0x6b, 0xdb, 0x40,
-	0x10, 0xae, 0x6c, 0x79, 0x1b, 0x4d, 0x84, 0x11, 0xdb, 0xb4, 0x88, 0xd2, 0x43, 0xd0, 0xa1, 0xc9,
-	0xa1, 0xb8, 0xe0, 0x5c, 0x7a, 0xe8, 0xa5, 0x38, 0x6e, 0x1b, 0x92, 0x9a, 0xb0, 0x09, 0xb9, 0xaf,
-	0xa5, 0xc1, 0x5e, 0xac, 0x17, 0xbb, 0xeb, 0x82, 0xfa, 0x43, 0xfa, 0x7b, 0xcb, 0x3e, 0x2c, 0xab,
-	0x90, 0x43, 0x4e, 0xda, 0x6f, 0xe6, 0x7b, 0xcc, 0x68, 0x17, 0xde, 0x56, 0xa8, 0x14, 0xdf, 0xe0,
-	0x67, 0xff, 0x9d, 0xb5, 0xb2, 0xd1, 0x0d, 0x25, 0x1b, 0x95, 0x6f, 0xf7, 0xeb, 0xec, 0x02, 0xa2,
-	0xfb, 0xfd, 0xba, 0x14, 0xf9, 0x2d, 0x76, 0x34, 0x86, 0x60, 0x99, 0x06, 0xe7, 0xc1, 0x65, 0xc4,
-	0x82, 0xa5, 0x41, 0xab, 0x74, 0xe4, 0xd0, 0x2a, 0x5b, 0x42, 0xf4, 0xb0, 0xe5, 0x12, 0x0b, 0x43,
-	0x4c, 0x60, 0xbc, 0xc3, 0xce, 0x52, 0x63, 0x66, 0x8e, 0xf4, 0x23, 0x90, 0x5c, 0xb4, 0x5b, 0x94,
-	0x56, 0x71, 0x3a, 0x9f, 0xce, 0x5c, 0xc0, 0x6c, 0x61, 0xab, 0xcc, 0x77, 0xb3, 0x4f, 0x40, 0x5c,
-	0x85, 0x4e, 0x61, 0x74, 0xf3, 0xe4, 0x2d, 0x46, 0x37, 0x4f, 0x94, 0x42, 0x58, 0x70, 0xcd, 0xad,
-	0x3e, 0x66, 0xf6, 0x9c, 0x7d, 0x87, 0xd8, 0xb1, 0x1f, 0x45, 0xbe, 0x43, 0x6d, 0x35, 0xd7, 0xbd,
-	0xe6, 0xfa, 0xc5, 0xa9, 0x77, 0x40, 0x16, 0xa5, 0xc0, 0x7a, 0xe8, 0x10, 0x59, 0x87, 0x33, 0x98,
-	0xe8, 0x66, 0x87, 0xb5, 0x5f, 0xd4, 0x01, 0xfa, 0x01, 0x22, 0x5e, 0x0a, 0xae, 0x56, 0xbc, 0xc2,
-	0x74, 0x6c, 0x3b, 0xc7, 0x42, 0xf6, 0x07, 0x88, 0x9f, 0x27, 0x85, 0xd7, 0xbc, 0x28, 0x24, 0x2a,
-	0xe5, 0x2d, 0x0f, 0xd0, 0x38, 0x28, 0xcc, 0x25, 0xea, 0x5b, 0xec, 0xbc, 0xf7, 0xb1, 0x40, 0xbf,
-	0x40, 0x9c, 0xdb, 0x79, 0x9c, 0x8f, 0x8d, 0x38, 0x9d, 0x9f, 0xf5, 0xd3, 0x0f, 0x7a, 0xec, 0x3f,
-	0x66, 0xf6, 0x15, 0xe2, 0x61, 0x97, 0xbe, 0x03, 0x92, 0x37, 0x75, 0xdd, 0xef, 0xe4, 0xd1, 0xf3,
-	0x7b, 0x65, 0x7f, 0x03, 0x20, 0x77, 0xa8, 0x35, 0x4a, 0x7a, 0x01, 0xa1, 0xee, 0x5a, 0xb4, 0xb2,
-	0xe9, 0xfc, 0xcd, 0x21, 0xda, 0x75, 0x67, 0x8f, 0x5d, 0x8b, 0xcc, 0x12, 0xe8, 0x7b, 0x38, 0x91,
-	0x98, 0xa3, 0xf8, 0xed, 0xff, 0x72, 0xc4, 0x7a, 0xdc, 0xdf, 0xd9, 0x78, 0x70, 0x67, 0x57, 0x10,
-	0x1a, 0x35, 0x05, 0x20, 0x0f, 0xa2, 0xde, 0x94, 0x98, 0xbc, 0xa2, 0x11, 0x4c, 0x7e, 0xc8, 0x66,
-	0xdf, 0x26, 0x01, 0x3d, 0x81, 0xf0, 0x5e, 0xd4, 0x9b, 0x64, 0x64, 0x08, 0x0c, 0x6b, 0x5e, 0x61,
-	0x32, 0xce, 0x10, 0x26, 0x0c, 0xdb, 0xb2, 0x33, 0xfb, 0x28, 0xac, 0x0b, 0x94, 0x87, 0x7d, 0x1c,
-	0x32, 0x49, 0x3f, 0x7f, 0x7d, 0x5b, 0x1c, 0x5e, 0x87, 0x39, 0x3f, 0x97, 0x6e, 0xfe, 0xbb, 0x16,
-	0x15, 0x2a, 0xcd, 0xab, 0x36, 0x0d, 0xcf, 0x83, 0xcb, 0x09, 0x3b, 0x16, 0xd6, 0xc4, 0x3e, 0xfe,
-	0xab, 0x7f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x41, 0x5d, 0x65, 0xe3, 0x15, 0x03, 0x00, 0x00,
+	// 862 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x5b, 0x6f, 0xe3, 0x54,
+	0x10, 0xae, 0x2f, 0x71, 0xe2, 0x89, 0xb7, 0x1c, 0x0e, 0xdd, 0x95, 0x85, 0x56, 0xa8, 0x98, 0x15,
+	0x5b, 0x2e, 0x0a, 0x25, 0x15, 0x12, 0x0f, 0x08, 0x29, 0xb8, 0x61, 0x5b, 0xd2, 0x96, 0xe8, 0x74,
+	0xb5, 0x12, 0x4f, 0xab, 0x53, 0x7b, 0x94, 0x58, 0x76, 0x6c, 0x63, 0x3b, 0x15, 0xe6, 0x91, 0x1f,
+	0x80, 0x78, 0xe4, 0xc7, 0xf0, 0xe3, 0xd0, 0xb9, 0x38, 0x49, 0xc3, 0x72, 0x7b, 0xca, 0xf9, 0xe6,
+	0x4c, 0x66, 0xbe, 0xf9, 0x66, 0xe6, 0x18, 0x1e, 0xaf, 0xb0, 0xae, 0xf9, 0x02, 0x3f, 0xd3, 0xbf,
+	0xa3, 0xb2, 0x2a, 0x9a, 0x82, 0x3a, 0x8b, 0x3a, 0x5a, 0xae, 0xef, 0x82, 0xe7, 0xe0, 0xce, 0xd7,
+	0x77, 0x59, 0x12, 0xcd, 0xb0, 0xa5, 0x1e, 0x18, 0x53, 0xdf, 0x38, 0x36, 0x4e, 0x5c, 0x66, 0x4c,
+	0x05, 0xba, 0xf1, 0x4d, 0x85, 0x6e, 0x82, 0xdf, 0x0c, 0x70, 0x6f, 0x97, 0xbc, 0xc2, 0x58, 0x78,
+	0x12, 0xb0, 0x52, 0x6c, 0xa5, 0xaf, 0xc7, 0xc4, 0x91, 0x7e, 0x08, 0x4e, 0x94, 0x94, 0x4b, 0xac,
+	0xe4, 0x5f, 0x86, 0xe3, 0xc3, 0x91, 0xca, 0x30, 0x0a, 0xa5, 0x95, 0xe9, 0x5b, 0xfa, 0x11, 0xf4,
+	0xea, 0x75, 0xd2, 0xa0, 0x6f, 0x1d, 0x1b, 0x27, 0x87, 0xe3, 0x77, 0x1e, 0xba, 0xdd, 0x8a, 0x2b,
+	0xa6, 0x3c, 0xe8, 0x7b, 0x00, 0xf8, 0x53, 0xb4, 0xe4, 0xf9, 0x02, 0x2f, 0xcf, 0x7d, 0x5b, 0x32,
+	0xd9, 0xb1, 0x04, 0xa7, 0xe0, 0x4d, 0xcb, 0x25, 0xae, 0xb0, 0xe2, 0x99, 0x20, 0x75, 0x08, 0xe6,
+	0xe5, 0xb9, 0xe6, 0x6f, 0x5e, 0x9e, 0x77, 0x24, 0xcd, 0x0d, 0xc9, 0xe0, 0x53, 0x70, 0x54, 0x1e,
+	0xe9, 0xfb, 0x4a, 0xf3, 0x37, 0x2f, 0x5f, 0x51, 0x0a, 0x76, 0xcc, 0x1b, 0xae, 0x9d, 0xe5, 0x39,
+	0xf8, 0x16, 0x3c, 0xe5, 0xfd, 0x32, 0x89, 0x52, 0x6c, 0x76, 0xe2, 0x7b, 0x32, 0xfe, 0x7f, 0x2c,
+	0x39, 0xb8, 0x02, 0x27, 0xcc, 0x12, 0xcc, 0x9b, 0xbf, 0x30, 0x3c, 0x82, 0x5e, 0x53, 0xa4, 0x98,
+	0x6b, 0x99, 0x15, 0xa0, 0x4f, 0xc1, 0xe5, 0x59, 0xc2, 0xeb, 0x1b, 0xbe, 0x52, 0x32, 0xb9, 0x6c,
+	0x6b, 0x08, 0x7e, 0x06, 0x47, 0xf3, 0xf1, 0xa1, 0xcf, 0xe3, 0xb8, 0xc2, 0xba, 0xd6, 0x21, 0x3b,
+	0x28, 0x22, 0xd4, 0x18, 0x55, 0xd8, 0xcc, 0x74, 0xfd, 0x2e, 0xdb, 0x1a, 0xe8, 0x97, 0xe0, 0x45,
+	0x92, 0x8f, 0x8a, 0x23, 0x53, 0x0c, 0xc7, 0x47, 0x1b, 0xf6, 0x3b, 0x77, 0xec, 0x81, 0x67, 0xf0,
+	0x15, 0x78, 0xbb, 0xb7, 0xf4, 0x09, 0x38, 0x51, 0x91, 0xe7, 0x9b, 0x9a, 0x34, 0x7a, 0x73, 0x5d,
+	0xc1, 0x2f, 0x16, 0x38, 0x57, 0xd8, 0x34, 0x58, 0xd1, 0xe7, 0x60, 0x37, 0x6d, 0x89, 0xbe, 0xf1,
+	0x70, 0x08, 0xd4, 0xed, 0xe8, 0x65, 0x5b, 0x22, 0x93, 0x0e, 0xf4, 0x5d, 0x18, 0x54, 0x18, 0x61,
+	0x72, 0xaf, 0x55, 0x76, 0xd9, 0x06, 0x6f, 0x7a, 0x66, 0x6d, 0x7b, 0xa6, 0x15, 0xb6, 0x37, 0x0a,
+	0x9f, 0x80, 0xc5, 0xa3, 0xd4, 0xef, 0xc9, 0x3c, 0x4f, 0xf6, 0xf2, 0x4c, 0xa2, 0xf4, 0xba, 0x88,
+	0x91, 0x09, 0x17, 0xa9, 0x59, 0xb2, 0xc8, 0x79, 0xb3, 0xae, 0xd0, 0x77, 0x64, 0xc8, 0xad, 0x41,
+	0xf0, 0xa8, 0xf1, 0xc7, 0x35, 0xe6, 0x11, 0xfa, 0xfd, 0x63, 0xe3, 0xc4, 0x66, 0x1b, 0x1c, 0xdc,
+	0x83, 0x2d, 0x18, 0x53, 0x00, 0xe7, 0x36, 0xc9, 0x17, 0x19, 0x92, 0x03, 0xea, 0x42, 0xef, 0x45,
+	0x55, 0xac, 0x4b, 0x62, 0xd0, 0x01, 0xd8, 0xf3, 0x24, 0x5f, 0x10, 0x53, 0x38, 0x30, 0xcc, 0xf9,
+	0x0a, 0x89, 0x45, 0x1f, 0x81, 0xfb, 0x5d, 0x91, 0xe4, 0xca, 0xc9, 0xa6, 0x87, 0x00, 0x57, 0xc8,
+	0xef, 0x51, 0xe1, 0x9e, 0xc4, 0x49, 0xdd, 0x48, 0x58, 0x13, 0x87, 0xf6, 0xc1, 0x9a, 0x44, 0x29,
+	0xe9, 0x8b, 0xc0, 0x0c, 0x53, 0x6c, 0xc9, 0x20, 0x38, 0x83, 0xbe, 0xae, 0x80, 0x0e, 0xe5, 0xf1,
+	0xa6, 0xc8, 0x45, 0x6e, 0x00, 0x67, 0x12, 0xa5, 0x17, 0xeb, 0x3b, 0x62, 0xd0, 0xb7, 0x60, 0x38,
+	0x89, 0x52, 0xa6, 0x25, 0x23, 0x66, 0xf0, 0xbb, 0x29, 0x02, 0x94, 0x59, 0x2b, 0x9a, 0x57, 0x63,
+	0x1e, 0x63, 0xd5, 0x35, 0x4f, 0x21, 0x21, 0xeb, 0xc5, 0xf5, 0x24, 0xec, 0x56, 0x41, 0x9c, 0xdf,
+	0x28, 0xf5, 0x53, 0x70, 0x9b, 0x64, 0x85, 0x75, 0xc3, 0x57, 0xa5, 0x54, 0xbc, 0xc7, 0xb6, 0x86,
+	0x4d, 0x87, 0x7b, 0xff, 0xd6, 0xe1, 0x23, 0xe8, 0x2d, 0x44, 0x95, 0x52, 0x73, 0x97, 0x29, 0xa0,
+	0xfb, 0xd8, 0xdf, 0xef, 0xe3, 0xe0, 0x7f, 0xf6, 0xd1, 0xfd, 0xa7, 0x3e, 0xc2, 0x5e, 0x1f, 0x3f,
+	0x00, 0x57, 0xea, 0x2d, 0x84, 0x17, 0xea, 0x48, 0x26, 0x62, 0xb7, 0x2c, 0xa1, 0x8e, 0x42, 0xc1,
+	0x1f, 0x06, 0x58, 0x6c, 0x1e, 0xd2, 0x67, 0x60, 0xa7, 0x49, 0x1e, 0xeb, 0x09, 0x26, 0x1d, 0x23,
+	0x36, 0x0f, 0x47, 0xb3, 0x24, 0x8f, 0x99, 0xbc, 0xa5, 0xcf, 0xe0, 0x51, 0x54, 0x54, 0x15, 0x66,
+	0xbc, 0x49, 0x0a, 0xb1, 0x27, 0x6a, 0x86, 0x1f, 0x1a, 0x45, 0xae, 0x15, 0x36, 0xcb, 0x22, 0xd6,
+	0xdb, 0xae, 0x91, 0x58, 0xf0, 0x92, 0xb7, 0x59, 0xc1, 0x63, 0xa9, 0xaf, 0xc7, 0x3a, 0x28, 0x44,
+	0xc3, 0xaa, 0x2a, 0x2a, 0x29, 0xaf, 0xcb, 0x14, 0x08, 0xde, 0x07, 0x5b, 0xe4, 0x16, 0xd3, 0xc0,
+	0x44, 0x51, 0x75, 0x43, 0x0e, 0xa8, 0x07, 0x03, 0x86, 0x75, 0x59, 0xe4, 0x35, 0x12, 0x23, 0xf8,
+	0xd5, 0x00, 0x6b, 0x3a, 0x9e, 0xfe, 0x1d, 0xfd, 0xe9, 0x78, 0xba, 0x4b, 0x5f, 0x75, 0xc1, 0xdc,
+	0x7f, 0x51, 0xad, 0xed, 0xb3, 0xdf, 0x0d, 0x86, 0xbd, 0xf3, 0x6e, 0x7e, 0xa2, 0x69, 0x88, 0x7d,
+	0x40, 0x9e, 0x61, 0x4c, 0x0e, 0xc4, 0x3c, 0xcf, 0xb0, 0xed, 0x58, 0x19, 0x62, 0x9e, 0x67, 0xd8,
+	0x12, 0xf3, 0xe3, 0xaf, 0x61, 0xb8, 0xf3, 0xf4, 0xcb, 0x41, 0x9e, 0xde, 0xbe, 0x0e, 0xbf, 0x09,
+	0xc9, 0x41, 0x07, 0x5e, 0x84, 0xd7, 0xc4, 0xa0, 0x8f, 0xe1, 0xed, 0xf0, 0x62, 0x12, 0x5e, 0x4c,
+	0xc6, 0xa7, 0xaf, 0xe7, 0xdf, 0x5f, 0xfd, 0xf0, 0xf9, 0xd9, 0xe9, 0x17, 0xc4, 0xbc, 0x73, 0xe4,
+	0xf7, 0xec, 0xec, 0xcf, 0x01, 0x00, 0x76, 0xac, 0xf8, 0x3c, 0xe8, 0x06, 0x00, 0x00,
 }
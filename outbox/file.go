@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	pb "github.com/gecosys/gsc-go/message"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Layout of the file:
+//  header: 8 bytes (little endian) - number of popped records at the beginning of file
+//  records: 4 bytes (little endian) size of record + 1 byte flags + letter (protobuf)
+const (
+	headerSize    = 8
+	flagEncrypted = byte(1)
+)
+
+// NewFile creates an outbox which keeps entries in the file at path,
+// so the entries survive restarting of the process.
+// Entries remaining in the file are loaded when the outbox is created.
+func NewFile(path string, capacity int, policy OverflowPolicy) (Outbox, error) {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &fileOutbox{
+		path:     path,
+		file:     file,
+		capacity: capacity,
+		policy:   policy,
+	}
+	err = f.load()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+type fileOutbox struct {
+	mtx      sync.Mutex
+	path     string
+	file     *os.File
+	entries  []*Entry
+	popped   uint64
+	capacity int
+	policy   OverflowPolicy
+}
+
+// load reads entries of the file, a record written partially is dropped
+func (f *fileOutbox) load() error {
+	data, err := ioutil.ReadAll(f.file)
+	if err != nil {
+		return err
+	}
+	if len(data) < headerSize {
+		return f.rewrite()
+	}
+
+	var (
+		popped = binary.LittleEndian.Uint64(data[:headerSize])
+		offset = headerSize
+		index  = uint64(0)
+	)
+	for offset+4 <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		if size < 1 || offset+4+size > len(data) {
+			break
+		}
+		record := data[offset+4 : offset+4+size]
+		offset += 4 + size
+
+		letter := new(pb.Letter)
+		err = proto.Unmarshal(record[1:], letter)
+		if err != nil {
+			break
+		}
+		if index >= popped {
+			f.entries = append(f.entries, &Entry{
+				Letter:      letter,
+				IsEncrypted: record[0]&flagEncrypted != 0,
+			})
+		}
+		index++
+	}
+	for len(f.entries) > f.capacity {
+		f.entries = f.entries[1:]
+	}
+	return f.rewrite()
+}
+
+// rewrite writes the remaining entries to a new file and replaces the current one
+func (f *fileOutbox) rewrite() error {
+	tmpPath := f.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(make([]byte, headerSize))
+	for idx := 0; err == nil && idx < len(f.entries); idx++ {
+		var record []byte
+		record, err = encode(f.entries[idx])
+		if err == nil {
+			_, err = tmp.Write(record)
+		}
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, f.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	f.file.Close()
+	f.file = tmp
+	f.popped = 0
+	return nil
+}
+
+func encode(entry *Entry) ([]byte, error) {
+	data, err := proto.Marshal(entry.Letter)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, 5+len(data))
+	binary.LittleEndian.PutUint32(record, uint32(1+len(data)))
+	if entry.IsEncrypted {
+		record[4] = flagEncrypted
+	}
+	copy(record[5:], data)
+	return record, nil
+}
+
+func (f *fileOutbox) Push(entry *Entry) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if len(f.entries) >= f.capacity {
+		switch f.policy {
+		case DropNewest:
+			return nil
+		case Reject:
+			return ErrFull
+		default:
+			err := f.pop()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	record, err := encode(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Write(record)
+	if err != nil {
+		return err
+	}
+	err = f.file.Sync()
+	if err != nil {
+		return err
+	}
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fileOutbox) Peek() (*Entry, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if len(f.entries) == 0 {
+		return nil, nil
+	}
+	return f.entries[0], nil
+}
+
+func (f *fileOutbox) Pop() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.pop()
+}
+
+func (f *fileOutbox) pop() error {
+	if len(f.entries) == 0 {
+		return nil
+	}
+	f.entries[0] = nil
+	f.entries = f.entries[1:]
+
+	// Compact the file when it contains too many popped records
+	if len(f.entries) == 0 || f.popped+1 >= uint64(f.capacity) {
+		return f.rewrite()
+	}
+
+	f.popped++
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint64(header, f.popped)
+	_, err := f.file.WriteAt(header, 0)
+	if err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *fileOutbox) Len() int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return len(f.entries)
+}
+
+func (f *fileOutbox) Close() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "outbox"), func() { os.RemoveAll(dir) }
+}
+
+func newEntry(receiver string, isEncrypted bool) *Entry {
+	return &Entry{
+		Letter: &pb.Letter{
+			Type:     pb.Letter_Single,
+			Receiver: receiver,
+			Data:     []byte("data of " + receiver),
+		},
+		IsEncrypted: isEncrypted,
+	}
+}
+
+func openFile(t *testing.T, path string, capacity int, policy OverflowPolicy) Outbox {
+	box, err := NewFile(path, capacity, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func push(t *testing.T, box Outbox, receivers ...string) {
+	for idx, receiver := range receivers {
+		err := box.Push(newEntry(receiver, idx%2 == 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// expect pops every entry of box and compares their receivers with receivers
+func expect(t *testing.T, box Outbox, receivers ...string) {
+	t.Helper()
+	if box.Len() != len(receivers) {
+		t.Fatalf("Len() = %d, want %d", box.Len(), len(receivers))
+	}
+	for _, receiver := range receivers {
+		entry, err := box.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil {
+			t.Fatalf("Peek() = nil, want %s", receiver)
+		}
+		if entry.Letter.Receiver != receiver || string(entry.Letter.Data) != "data of "+receiver {
+			t.Fatalf("Peek() = %s, want %s", entry.Letter.Receiver, receiver)
+		}
+		err = box.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry, err := box.Peek()
+	if err != nil || entry != nil {
+		t.Fatalf("Peek() of empty outbox = %v, %v", entry, err)
+	}
+}
+
+func TestFilePushPop(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	box := openFile(t, path, 10, DropOldest)
+	defer box.Close()
+	push(t, box, "a", "b", "c")
+	expect(t, box, "a", "b", "c")
+
+	// Popping everything and pushing again keeps the order
+	push(t, box, "d", "e")
+	expect(t, box, "d", "e")
+}
+
+func TestFileReload(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	box := openFile(t, path, 10, DropOldest)
+	push(t, box, "a", "b", "c", "d")
+	err := box.Pop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.Close()
+
+	box = openFile(t, path, 10, DropOldest)
+	defer box.Close()
+	entry, _ := box.Peek()
+	if entry == nil || entry.IsEncrypted != false {
+		t.Fatalf("flags of reloaded entry are lost: %+v", entry)
+	}
+	expect(t, box, "b", "c", "d")
+}
+
+func TestFileCompaction(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	// Popping capacity records rewrites the file without them
+	box := openFile(t, path, 3, DropOldest)
+	push(t, box, "a", "b", "c")
+	for idx := 0; idx < 2; idx++ {
+		err := box.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(t, box, "d", "e")
+	err := box.Pop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	box.Close()
+
+	box = openFile(t, path, 3, DropOldest)
+	defer box.Close()
+	expect(t, box, "d", "e")
+}
+
+func TestFileTruncatedTail(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	box := openFile(t, path, 10, DropOldest)
+	push(t, box, "a", "b", "c")
+	box.Close()
+
+	// Cut the last record in the middle, like a crash while writing it
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Truncate(path, info.Size()-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box = openFile(t, path, 10, DropOldest)
+	expect(t, box, "a", "b")
+
+	// New records are appended after the dropped one
+	push(t, box, "d")
+	box.Close()
+
+	box = openFile(t, path, 10, DropOldest)
+	defer box.Close()
+	expect(t, box, "d")
+}
+
+func TestFileOverflow(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	box := openFile(t, path, 2, DropOldest)
+	push(t, box, "a", "b", "c")
+	expect(t, box, "b", "c")
+	box.Close()
+
+	box = openFile(t, path, 2, DropNewest)
+	push(t, box, "a", "b", "c")
+	expect(t, box, "a", "b")
+	box.Close()
+
+	box = openFile(t, path, 2, Reject)
+	defer box.Close()
+	push(t, box, "a", "b")
+	err := box.Push(newEntry("c", false))
+	if err != ErrFull {
+		t.Fatalf("Push() to full outbox = %v, want ErrFull", err)
+	}
+	expect(t, box, "a", "b")
+}
@@ -0,0 +1,77 @@
+package outbox
+
+import "sync"
+
+// NewMemory creates an outbox keeping at most capacity entries in memory
+func NewMemory(capacity int, policy OverflowPolicy) Outbox {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &memory{
+		entries: make([]*Entry, capacity),
+		policy:  policy,
+	}
+}
+
+// memory is a ring buffer of entries
+type memory struct {
+	mtx     sync.Mutex
+	entries []*Entry
+	head    int
+	size    int
+	policy  OverflowPolicy
+}
+
+func (m *memory) Push(entry *Entry) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.size == len(m.entries) {
+		switch m.policy {
+		case DropNewest:
+			return nil
+		case Reject:
+			return ErrFull
+		default:
+			m.pop()
+		}
+	}
+	m.entries[(m.head+m.size)%len(m.entries)] = entry
+	m.size++
+	return nil
+}
+
+func (m *memory) Peek() (*Entry, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.size == 0 {
+		return nil, nil
+	}
+	return m.entries[m.head], nil
+}
+
+func (m *memory) Pop() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.pop()
+	return nil
+}
+
+func (m *memory) pop() {
+	if m.size == 0 {
+		return
+	}
+	m.entries[m.head] = nil
+	m.head = (m.head + 1) % len(m.entries)
+	m.size--
+}
+
+func (m *memory) Len() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.size
+}
+
+func (m *memory) Close() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"errors"
+
+	pb "github.com/gecosys/gsc-go/message"
+)
+
+// ErrFull is returned by Push when the outbox is full and its policy is Reject
+var ErrFull = errors.New("Outbox is full")
+
+// OverflowPolicy decides what happens when pushing to a full outbox
+type OverflowPolicy int
+
+const (
+	// DropOldest removes the oldest entry to keep the new one
+	DropOldest OverflowPolicy = iota
+	// DropNewest keeps the outbox unchanged and drops the new entry silently
+	DropNewest
+	// Reject keeps the outbox unchanged and returns ErrFull
+	Reject
+)
+
+// Entry is a letter waiting for the connection to be re-opened
+type Entry struct {
+	Letter      *pb.Letter
+	IsEncrypted bool
+}
+
+// Outbox queues letters in order while the connection to GSCHub is lost.
+// Implementations must be safe for concurrent use.
+type Outbox interface {
+	// Push appends entry to the end of the queue
+	Push(entry *Entry) error
+	// Peek returns the oldest entry without removing it, it returns nil if the queue is empty
+	Peek() (*Entry, error)
+	// Pop removes the oldest entry
+	Pop() error
+	// Len returns the number of entries in the queue
+	Len() int
+	// Close releases resources of the outbox
+	Close() error
+}
@@ -0,0 +1,201 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gecosys/gsc-go/client"
+	"github.com/gecosys/gsc-go/internal/demux"
+	"github.com/gecosys/gsc-go/internal/randid"
+	pb "github.com/gecosys/gsc-go/message"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// DefaultTimeout is the limit of time to wait for a response
+// when the context of Call has no deadline
+const DefaultTimeout = 5 * time.Second
+
+// magic prefixes data of messages carrying RPC,
+// so they are not mixed with other messages
+var magic = []byte{0x00, 'R', 'P', 'C'}
+
+// ErrClosed is returned when the peer or its client is closed
+var ErrClosed = errors.New("RPC peer is closed")
+
+// RemoteError is returned by Call when the handler on the receiver's side fails
+type RemoteError struct {
+	Method  string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("RPC %s: %s", e.Method, e.Message)
+}
+
+// Handler serves a request sent by sender, the returned data is the response
+type Handler func(ctx context.Context, sender string, payload []byte) ([]byte, error)
+
+// Options configures a Peer
+type Options struct {
+	// Timeout is used when the context of Call has no deadline (default: DefaultTimeout)
+	Timeout time.Duration
+	// DisableEncryption sends requests and responses without encryption
+	DisableEncryption bool
+}
+
+// Peer sends requests to other connections and serves their requests.
+// It consumes channels of GEHClient.Listen, so the client must not be read
+// directly or shared with another peer. Other messages and errors are passed to
+// the channels of Listen of the peer once it is called, and dropped before.
+type Peer struct {
+	client      client.GEHClient
+	options     Options
+	demux       *demux.Demux
+	mtxHandlers sync.RWMutex
+	handlers    map[string]Handler
+	mtxCalls    sync.Mutex
+	calls       map[string]chan *pb.RPC
+}
+
+// New creates a Peer working over c, c should be opened by the caller
+func New(c client.GEHClient, opts *Options) *Peer {
+	p := &Peer{
+		client:   c,
+		handlers: make(map[string]Handler),
+		calls:    make(map[string]chan *pb.RPC),
+	}
+	if opts != nil {
+		p.options = *opts
+	}
+	if p.options.Timeout <= 0 {
+		p.options.Timeout = DefaultTimeout
+	}
+	p.demux = demux.New(magic, p.dispatch)
+	go p.demux.Run(c.Listen())
+	return p
+}
+
+// Handle registers handler of method, it replaces the previous handler of the method
+func (p *Peer) Handle(method string, handler Handler) {
+	p.mtxHandlers.Lock()
+	defer p.mtxHandlers.Unlock()
+	p.handlers[method] = handler
+}
+
+// Listen returns channels of messages and errors which are not RPC,
+// they are closed when the client is closed.
+// After the first call, the channels must be drained or the peer stops dispatching.
+func (p *Peer) Listen() (chan *client.GEHMessage, chan error) {
+	return p.demux.Listen()
+}
+
+// Call sends request to receiver and waits for its response
+func (p *Peer) Call(ctx context.Context, receiver, method string, payload []byte) ([]byte, error) {
+	if _, ok := ctx.Deadline(); ok == false {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.Timeout)
+		defer cancel()
+	}
+
+	id, err := randid.New()
+	if err != nil {
+		return nil, err
+	}
+
+	chanResponse := make(chan *pb.RPC, 1)
+	p.mtxCalls.Lock()
+	p.calls[id] = chanResponse
+	p.mtxCalls.Unlock()
+	defer func() {
+		p.mtxCalls.Lock()
+		delete(p.calls, id)
+		p.mtxCalls.Unlock()
+	}()
+
+	err = p.send(ctx, receiver, &pb.RPC{
+		Kind:          pb.RPC_Request,
+		CorrelationID: id,
+		Method:        method,
+		Payload:       payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-chanResponse:
+		if res.Error != "" {
+			return nil, &RemoteError{Method: method, Message: res.Error}
+		}
+		return res.Payload, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.demux.Context().Done():
+		return nil, ErrClosed
+	}
+}
+
+func (p *Peer) send(ctx context.Context, receiver string, msg *pb.RPC) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	buffer := make([]byte, 0, len(magic)+len(data))
+	buffer = append(buffer, magic...)
+	buffer = append(buffer, data...)
+	return p.client.SendMessageContext(ctx, receiver, buffer, p.options.DisableEncryption == false)
+}
+
+func (p *Peer) dispatch(msg *client.GEHMessage, payload []byte) {
+	rpc := new(pb.RPC)
+	err := proto.Unmarshal(payload, rpc)
+	if err != nil {
+		p.demux.ForwardError(err)
+		return
+	}
+
+	if rpc.Kind == pb.RPC_Response {
+		p.mtxCalls.Lock()
+		chanResponse, ok := p.calls[rpc.CorrelationID]
+		p.mtxCalls.Unlock()
+		if ok {
+			select {
+			case chanResponse <- rpc:
+			default:
+			}
+		}
+		return
+	}
+
+	p.mtxHandlers.RLock()
+	handler, ok := p.handlers[rpc.Method]
+	p.mtxHandlers.RUnlock()
+
+	ctx := p.demux.Context()
+	p.demux.Go(func() {
+		res := &pb.RPC{
+			Kind:          pb.RPC_Response,
+			CorrelationID: rpc.CorrelationID,
+			Method:        rpc.Method,
+		}
+		if ok == false {
+			res.Error = "Method not found"
+		} else {
+			payload, err := handler(ctx, msg.Sender, rpc.Payload)
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Payload = payload
+			}
+		}
+
+		err := p.send(ctx, msg.Sender, res)
+		if err != nil && ctx.Err() == nil {
+			p.demux.ForwardError(err)
+		}
+	})
+}
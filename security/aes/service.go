@@ -4,10 +4,20 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/binary"
-	"math/rand"
+	"io"
+
+	"github.com/gecosys/gsc-go/security/internal/cryptoerr"
 )
 
-func Encrypt(key, data []byte) (iv, output []byte, err error) {
+// ErrInvalidCipher is returned when encrypted data is malformed
+var ErrInvalidCipher = cryptoerr.ErrInvalidCipher
+
+// ErrAuthentication is returned when encrypted data has been tampered
+var ErrAuthentication = cryptoerr.ErrAuthentication
+
+// Encrypt encrypts data with AES-CBC, size of data is prepended to data before encrypting.
+// IV is read from random, it should be crypto/rand.Reader.
+func Encrypt(random io.Reader, key, data []byte) (iv, output []byte, err error) {
 	var block cipher.Block
 	block, err = aes.NewCipher(key)
 	if err != nil {
@@ -15,7 +25,7 @@ func Encrypt(key, data []byte) (iv, output []byte, err error) {
 	}
 
 	iv = make([]byte, 16)
-	_, err = rand.Read(iv)
+	_, err = io.ReadFull(random, iv)
 	if err != nil {
 		return
 	}
@@ -29,19 +39,70 @@ func Encrypt(key, data []byte) (iv, output []byte, err error) {
 	return
 }
 
+// Decrypt decrypts data encrypted by Encrypt.
+// CBC mode is not authenticated, so tampered data may be decrypted to garbage.
 func Decrypt(key, iv, data []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return []byte{}, err
 	}
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return []byte{}, ErrInvalidCipher
+	}
 
 	stream := cipher.NewCBCDecrypter(block, iv)
 	output := make([]byte, len(data))
 	stream.CryptBlocks(output, data)
 	size := binary.LittleEndian.Uint32(output[:4])
+	if uint64(size) > uint64(len(output)-4) {
+		return []byte{}, ErrInvalidCipher
+	}
 	return output[4 : 4+size], nil
 }
 
+// EncryptGCM encrypts data with AES-GCM, key must be 16, 24 or 32 bytes.
+// Nonce is read from random, it should be crypto/rand.Reader.
+func EncryptGCM(random io.Reader, key, data []byte) (nonce, output []byte, err error) {
+	var aead cipher.AEAD
+	aead, err = newGCM(key)
+	if err != nil {
+		return
+	}
+
+	nonce = make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(random, nonce)
+	if err != nil {
+		return
+	}
+	output = aead.Seal(nil, nonce, data, nil)
+	return
+}
+
+// DecryptGCM decrypts and authenticates data encrypted by EncryptGCM
+func DecryptGCM(key, nonce, data []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(nonce) != aead.NonceSize() || len(data) < aead.Overhead() {
+		return []byte{}, ErrInvalidCipher
+	}
+
+	output, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return []byte{}, ErrAuthentication
+	}
+	return output, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 func calcEncryptedSize(length uint32) uint32 {
 	length += 4
 	bufferSize := (length / 16) * 16 // block_size = 16
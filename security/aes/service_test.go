@@ -0,0 +1,119 @@
+package aes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestGCMRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		keySize int
+		data    []byte
+	}{
+		{"AES-128 empty", 16, []byte{}},
+		{"AES-192 short", 24, []byte("a")},
+		{"AES-256 short", 32, []byte("hello")},
+		{"AES-256 long", 32, bytes.Repeat([]byte("0123456789"), 1000)},
+	}
+	for _, test := range tests {
+		key := make([]byte, test.keySize)
+		rand.Read(key)
+
+		nonce, encrypted, err := EncryptGCM(rand.Reader, key, test.data)
+		if err != nil {
+			t.Fatalf("%s: EncryptGCM() error = %v", test.name, err)
+		}
+		decrypted, err := DecryptGCM(key, nonce, encrypted)
+		if err != nil {
+			t.Fatalf("%s: DecryptGCM() error = %v", test.name, err)
+		}
+		if bytes.Equal(decrypted, test.data) == false {
+			t.Fatalf("%s: DecryptGCM() = %q, want %q", test.name, decrypted, test.data)
+		}
+	}
+}
+
+func TestGCMErrors(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	nonce, encrypted, err := EncryptGCM(rand.Reader, key, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte(nil), encrypted...)
+	flipped[0] ^= 1
+	otherKey := make([]byte, 32)
+
+	tests := []struct {
+		name  string
+		key   []byte
+		nonce []byte
+		data  []byte
+		want  error
+	}{
+		{"flipped bit", key, nonce, flipped, ErrAuthentication},
+		{"wrong key", otherKey, nonce, encrypted, ErrAuthentication},
+		{"short data", key, nonce, encrypted[:10], ErrInvalidCipher},
+		{"empty data", key, nonce, nil, ErrInvalidCipher},
+		{"short nonce", key, nonce[:5], encrypted, ErrInvalidCipher},
+		{"empty nonce", key, nil, encrypted, ErrInvalidCipher},
+	}
+	for _, test := range tests {
+		_, err := DecryptGCM(test.key, test.nonce, test.data)
+		if err != test.want {
+			t.Errorf("%s: DecryptGCM() error = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestCBCRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	for _, data := range [][]byte{{}, []byte("a"), []byte("exactly twelve"), bytes.Repeat([]byte("x"), 1000)} {
+		iv, encrypted, err := Encrypt(rand.Reader, key, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := Decrypt(key, iv, encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt() error = %v", err)
+		}
+		if bytes.Equal(decrypted, data) == false {
+			t.Fatalf("Decrypt() = %q, want %q", decrypted, data)
+		}
+	}
+}
+
+func TestCBCErrors(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	iv, encrypted, err := Encrypt(rand.Reader, key, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A size larger than the data is only found after decrypting
+	_, oversized, err := Encrypt(rand.Reader, key, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oversized[0] ^= 0xff
+
+	tests := []struct {
+		name string
+		iv   []byte
+		data []byte
+	}{
+		{"odd length", iv, encrypted[:len(encrypted)-1]},
+		{"empty data", iv, nil},
+		{"short iv", iv[:8], encrypted},
+		{"garbage size", iv, oversized},
+	}
+	for _, test := range tests {
+		_, err := Decrypt(key, test.iv, test.data)
+		if err != ErrInvalidCipher {
+			t.Errorf("%s: Decrypt() error = %v, want ErrInvalidCipher", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package chacha20
+
+import (
+	"crypto/cipher"
+	"io"
+
+	"github.com/gecosys/gsc-go/security/internal/cryptoerr"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrInvalidCipher is returned when encrypted data is malformed
+var ErrInvalidCipher = cryptoerr.ErrInvalidCipher
+
+// ErrAuthentication is returned when encrypted data has been tampered
+var ErrAuthentication = cryptoerr.ErrAuthentication
+
+// Encrypt encrypts data with ChaCha20-Poly1305, key must be 32 bytes.
+// Nonce is read from random, it should be crypto/rand.Reader.
+func Encrypt(random io.Reader, key, data []byte) (nonce, output []byte, err error) {
+	var aead cipher.AEAD
+	aead, err = chacha20poly1305.New(key)
+	if err != nil {
+		return
+	}
+
+	nonce = make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(random, nonce)
+	if err != nil {
+		return
+	}
+	output = aead.Seal(nil, nonce, data, nil)
+	return
+}
+
+// Decrypt decrypts and authenticates data encrypted by Encrypt
+func Decrypt(key, nonce, data []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(nonce) != aead.NonceSize() || len(data) < aead.Overhead() {
+		return []byte{}, ErrInvalidCipher
+	}
+
+	output, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return []byte{}, ErrAuthentication
+	}
+	return output, nil
+}
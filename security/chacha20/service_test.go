@@ -0,0 +1,70 @@
+package chacha20
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"short", []byte("hello")},
+		{"long", bytes.Repeat([]byte("0123456789"), 1000)},
+	}
+	for _, test := range tests {
+		nonce, encrypted, err := Encrypt(rand.Reader, key, test.data)
+		if err != nil {
+			t.Fatalf("%s: Encrypt() error = %v", test.name, err)
+		}
+		decrypted, err := Decrypt(key, nonce, encrypted)
+		if err != nil {
+			t.Fatalf("%s: Decrypt() error = %v", test.name, err)
+		}
+		if bytes.Equal(decrypted, test.data) == false {
+			t.Fatalf("%s: Decrypt() = %q, want %q", test.name, decrypted, test.data)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	nonce, encrypted, err := Encrypt(rand.Reader, key, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte(nil), encrypted...)
+	flipped[len(flipped)-1] ^= 1
+	otherKey := make([]byte, 32)
+
+	tests := []struct {
+		name  string
+		key   []byte
+		nonce []byte
+		data  []byte
+		want  error
+	}{
+		{"flipped bit", key, nonce, flipped, ErrAuthentication},
+		{"wrong key", otherKey, nonce, encrypted, ErrAuthentication},
+		{"short data", key, nonce, encrypted[:10], ErrInvalidCipher},
+		{"empty data", key, nonce, nil, ErrInvalidCipher},
+		{"short nonce", key, nonce[:5], encrypted, ErrInvalidCipher},
+	}
+	for _, test := range tests {
+		_, err := Decrypt(test.key, test.nonce, test.data)
+		if err != test.want {
+			t.Errorf("%s: Decrypt() error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	_, err = Decrypt(key[:16], nonce, encrypted)
+	if err == nil {
+		t.Error("Decrypt() with short key succeeded")
+	}
+}
@@ -0,0 +1,10 @@
+package cryptoerr
+
+import "errors"
+
+var (
+	// ErrInvalidCipher is returned when encrypted data is malformed
+	ErrInvalidCipher = errors.New("Invalid encrypted data")
+	// ErrAuthentication is returned when encrypted data has been tampered
+	ErrAuthentication = errors.New("Message authentication failed")
+)
@@ -1,6 +1,12 @@
 package rsa
 
-import "math/big"
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	stdrsa "crypto/rsa"
+)
 
 type (
 	PublicKey struct {
@@ -8,3 +14,27 @@ type (
 		N *big.Int
 	}
 )
+
+// ParsePublicKey creates public key from its exponent and modulus in decimal
+func ParsePublicKey(e, n string) (*PublicKey, error) {
+	bigE, ok := new(big.Int).SetString(e, 10)
+	if ok == false {
+		return nil, errors.New("Cannot setup public key")
+	}
+	bigN, ok := new(big.Int).SetString(n, 10)
+	if ok == false {
+		return nil, errors.New("Cannot setup public key")
+	}
+	return &PublicKey{E: bigE, N: bigN}, nil
+}
+
+// Std converts the key to crypto/rsa.PublicKey
+func (key *PublicKey) Std() (*stdrsa.PublicKey, error) {
+	if key.E.IsInt64() == false || key.E.Int64() > math.MaxInt32 {
+		return nil, errors.New("Invalid public exponent")
+	}
+	return &stdrsa.PublicKey{
+		N: key.N,
+		E: int(key.E.Int64()),
+	}, nil
+}
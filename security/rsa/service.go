@@ -1,14 +1,18 @@
 package rsa
 
 import (
+	stdrsa "crypto/rsa"
+	"crypto/sha256"
+	"io"
 	"math"
 	"math/big"
-	"math/rand"
 	"strings"
 )
 
-func Encrypt(key *PublicKey, data []byte) ([]byte, error) {
-	encodedData, err := encode(data)
+// Encrypt encrypts every byte of data with RSA, it is used by hubs older than 3.0.0.
+// Mask of data is read from random, it should be crypto/rand.Reader.
+func Encrypt(random io.Reader, key *PublicKey, data []byte) ([]byte, error) {
+	encodedData, err := encode(random, data)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -21,7 +25,7 @@ func Encrypt(key *PublicKey, data []byte) ([]byte, error) {
 	return content, nil
 }
 
-func encode(data []byte) ([]byte, error) {
+func encode(random io.Reader, data []byte) ([]byte, error) {
 	size := len(data)
 	mask := make([]byte, 32)
 	sizeMask := byte(math.Min(float64(size), 32))
@@ -29,7 +33,7 @@ func encode(data []byte) ([]byte, error) {
 
 	// Setup mask
 	buffer[0] = sizeMask
-	_, err := rand.Read(mask)
+	_, err := io.ReadFull(random, mask)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -43,3 +47,13 @@ func encode(data []byte) ([]byte, error) {
 	}
 	return buffer, nil
 }
+
+// EncryptOAEP encrypts data with RSA-OAEP (SHA-256),
+// data must be shorter than size of key - 66 bytes
+func EncryptOAEP(random io.Reader, key *PublicKey, data []byte) ([]byte, error) {
+	stdKey, err := key.Std()
+	if err != nil {
+		return []byte{}, err
+	}
+	return stdrsa.EncryptOAEP(sha256.New(), random, stdKey, data, nil)
+}
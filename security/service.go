@@ -3,89 +3,290 @@ package security
 import (
 	"crypto/rand"
 	"errors"
-	"math/big"
+	"io"
 
 	pb "github.com/gecosys/gsc-go/message"
 	aes "github.com/gecosys/gsc-go/security/aes"
+	chacha20 "github.com/gecosys/gsc-go/security/chacha20"
+	"github.com/gecosys/gsc-go/security/internal/cryptoerr"
 	rsa "github.com/gecosys/gsc-go/security/rsa"
+	x25519 "github.com/gecosys/gsc-go/security/x25519"
 
 	"github.com/golang/protobuf/proto"
 )
 
-// RSA public key
-var publicKey *rsa.PublicKey
+// CipherSuite is algorithm used to encrypt payloads with the shared key
+type CipherSuite int
 
-// AES key
-var sharedKey []byte
+// Values are the same as pb.CipherSuite
+const (
+	// CipherAESCBC is AES-256-CBC, it is supported by every hub
+	CipherAESCBC CipherSuite = iota
+	// CipherAESGCM is AES-256-GCM
+	CipherAESGCM
+	// CipherChaCha20Poly1305 is ChaCha20-Poly1305
+	CipherChaCha20Poly1305
+)
+
+var (
+	// ErrInvalidCipher is returned when encrypted data is malformed
+	ErrInvalidCipher = cryptoerr.ErrInvalidCipher
+	// ErrAuthentication is returned when encrypted data has been tampered
+	ErrAuthentication = cryptoerr.ErrAuthentication
+)
+
+// Handshake is how the shared key of a session is established
+type Handshake int
+
+const (
+	// HandshakeRSA generates the shared key on client and encrypts it with the public key of GSCHub
+	HandshakeRSA Handshake = iota
+	// HandshakeX25519 derives the shared key from ephemeral X25519 keys of both sides
+	HandshakeX25519
+)
+
+// KeyWrap is algorithm used to encrypt the shared key and connection's id with the public key
+type KeyWrap int
+
+const (
+	// KeyWrapAuto lets the client choose the algorithm, clients choose KeyWrapOAEP.
+	// It can't be used to create sessions
+	KeyWrapAuto KeyWrap = iota
+	// KeyWrapLegacy encrypts every byte with RSA, it is supported by every hub
+	KeyWrapLegacy
+	// KeyWrapOAEP is RSA-OAEP with SHA-256
+	KeyWrapOAEP
+)
+
+// infoX25519 binds keys derived by X25519 handshake to this protocol
+var infoX25519 = []byte("gsc-go shared key")
+
+// SessionOptions configures algorithms of a session
+type SessionOptions struct {
+	Cipher  CipherSuite
+	KeyWrap KeyWrap
+	// Rand is source of keys, IVs, nonces and masks (default: crypto/rand.Reader).
+	// It should only be replaced by deterministic readers in tests.
+	Rand io.Reader
+}
+
+// Session holds keys of one connection to GSCHub.
+// A new session is created for every handshake, so reconnecting never
+// changes the keys used by a socket which is still running.
+type Session struct {
+	// RSA public key
+	publicKey *rsa.PublicKey
+	// AES key
+	sharedKey []byte
+	cipher    CipherSuite
+	keyWrap   KeyWrap
+	random    io.Reader
+	// X25519 handshake
+	exchangeID  string
+	localPublic []byte
+}
+
+// NewSession creates session from public key returned by GSCHub
+// and generates a new shared key
+// Input:
+//  key: public key RSA (pb.PublicKey)
+//  opts: algorithms of the session
+// Output:
+//  session: the new session
+//  err: error occurred
+func NewSession(key []byte, opts SessionOptions) (session *Session, err error) {
+	if opts.Cipher < CipherAESCBC || opts.Cipher > CipherChaCha20Poly1305 {
+		return nil, errors.New("Unsupported cipher suite")
+	}
+	if opts.KeyWrap != KeyWrapLegacy && opts.KeyWrap != KeyWrapOAEP {
+		return nil, errors.New("Unsupported key wrap")
+	}
+
+	random := opts.Rand
+	if random == nil {
+		random = rand.Reader
+	}
 
-func Setup(key []byte) error {
 	// Generate shared key AES
-	sharedKey = make([]byte, 32)
-	_, err := rand.Read(sharedKey)
+	sharedKey := make([]byte, 32)
+	_, err = io.ReadFull(random, sharedKey)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Setup public key RSA
 	eKey := pb.PublicKey{}
 	err = proto.Unmarshal(key, &eKey)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	publicKey, err := rsa.ParsePublicKey(eKey.E, eKey.N)
+	if err != nil {
+		return nil, err
+	}
+
+	session = &Session{
+		publicKey: publicKey,
+		sharedKey: sharedKey,
+		cipher:    opts.Cipher,
+		keyWrap:   opts.KeyWrap,
+		random:    random,
+	}
+	return session, nil
+}
+
+// NewSessionX25519 creates session from ephemeral key returned by GSCHub.
+// The shared key is derived from X25519 and HKDF-SHA256, so it can't be recovered
+// from recorded traffic even if the long-term key of GSCHub is compromised.
+// KeyWrap of opts is not used.
+// Input:
+//  key: ephemeral key of GSCHub (pb.EphemeralKey)
+//  opts: algorithms of the session
+// Output:
+//  session: the new session
+//  err: error occurred
+func NewSessionX25519(key []byte, opts SessionOptions) (session *Session, err error) {
+	if opts.Cipher < CipherAESCBC || opts.Cipher > CipherChaCha20Poly1305 {
+		return nil, errors.New("Unsupported cipher suite")
+	}
+
+	random := opts.Rand
+	if random == nil {
+		random = rand.Reader
+	}
+
+	eKey := pb.EphemeralKey{}
+	err = proto.Unmarshal(key, &eKey)
+	if err != nil {
+		return nil, err
+	}
+	if eKey.ID == "" || len(eKey.Key) != x25519.KeySize {
+		return nil, errors.New("Cannot setup ephemeral key")
+	}
+
+	private, public, err := x25519.GenerateKey(random)
+	if err != nil {
+		return nil, err
 	}
-	e, ok := new(big.Int).SetString(eKey.E, 10)
-	if ok == false {
-		return errors.New("Cannot setup public key")
+
+	// Salt is the public keys of both sides, in order client + hub
+	salt := make([]byte, 0, 2*x25519.KeySize)
+	salt = append(salt, public...)
+	salt = append(salt, eKey.Key...)
+	sharedKey, err := x25519.DeriveKey(private, eKey.Key, salt, infoX25519, 32)
+	if err != nil {
+		return nil, err
 	}
-	n, ok := new(big.Int).SetString(eKey.N, 10)
-	if ok == false {
-		return errors.New("Cannot setup public key")
+
+	session = &Session{
+		sharedKey:   sharedKey,
+		cipher:      opts.Cipher,
+		random:      random,
+		exchangeID:  eKey.ID,
+		localPublic: public,
 	}
-	publicKey = new(rsa.PublicKey)
-	publicKey.E = e
-	publicKey.N = n
-	return nil
+	return session, nil
+}
+
+// GetExchangeID returns id of the ephemeral key of GSCHub,
+// it is empty if the session is not created by X25519 handshake
+func (s *Session) GetExchangeID() string {
+	return s.exchangeID
 }
 
-// GetSharedKey returns shared key encrypted by RSA
+// GetCipher returns algorithm used to encrypt payloads
+func (s *Session) GetCipher() CipherSuite {
+	return s.cipher
+}
+
+// GetKeyWrap returns algorithm used to encrypt with the public key
+func (s *Session) GetKeyWrap() KeyWrap {
+	return s.keyWrap
+}
+
+// GetSharedKey returns shared key encrypted by RSA,
+// or public key of client if the session is created by X25519 handshake
 // Output:
 //  output: the encrypted shared key
 //  err: error occurred
-func GetSharedKey() (output []byte, err error) {
-	output, err = EncryptRSA(sharedKey)
+func (s *Session) GetSharedKey() (output []byte, err error) {
+	if s.exchangeID != "" {
+		output = s.localPublic
+		return
+	}
+	output, err = s.EncryptRSA(s.sharedKey)
 	return
 }
 
-// EncryptRSA encrypts data with RSA
+// EncryptConnID returns connection's id encrypted by RSA,
+// or the plain id if the session is created by X25519 handshake
+// Output:
+//  output: the encrypted id
+//  err: error occurred
+func (s *Session) EncryptConnID(connID string) (output []byte, err error) {
+	if s.exchangeID != "" {
+		output = []byte(connID)
+		return
+	}
+	output, err = s.EncryptRSA([]byte(connID))
+	return
+}
+
+// EncryptRSA encrypts data with RSA by the key wrap of session
 // Input:
 //  data: content will be encrypted
 // Output:
 //  output: the encrypted data
 //  err: error occurred
-func EncryptRSA(data []byte) (output []byte, err error) {
-	output, err = rsa.Encrypt(publicKey, data)
+func (s *Session) EncryptRSA(data []byte) (output []byte, err error) {
+	if s.publicKey == nil {
+		err = errors.New("Session has no public key")
+		return
+	}
+	if s.keyWrap == KeyWrapOAEP {
+		output, err = rsa.EncryptOAEP(s.random, s.publicKey, data)
+		return
+	}
+	output, err = rsa.Encrypt(s.random, s.publicKey, data)
 	return
 }
 
-// Encrypt encrypts data with AES
+// Encrypt encrypts data with the shared key by the cipher suite of session
 // Input:
 //  data: content will be encrypted
 // Output:
-//  iv: vector AES
+//  iv: vector AES or nonce of AEAD suites
 //  output: the encrypted data
 //  err: error occurred
-func Encrypt(data []byte) (iv, output []byte, err error) {
-	iv, output, err = aes.Encrypt(sharedKey, data)
+func (s *Session) Encrypt(data []byte) (iv, output []byte, err error) {
+	switch s.cipher {
+	case CipherAESGCM:
+		iv, output, err = aes.EncryptGCM(s.random, s.sharedKey, data)
+	case CipherChaCha20Poly1305:
+		iv, output, err = chacha20.Encrypt(s.random, s.sharedKey, data)
+	default:
+		iv, output, err = aes.Encrypt(s.random, s.sharedKey, data)
+	}
 	return
 }
 
-// Decrypt decrypts data with AES
+// Decrypt decrypts data with the shared key by the cipher suite of session.
+// Malformed data returns ErrInvalidCipher and tampered data of AEAD suites
+// returns ErrAuthentication.
 // Input:
-//  iv: vector AES
+//  iv: vector AES or nonce of AEAD suites
 //  data: encrypted content
 // Output:
 //  output: the decrypted data
 //  err: error occurred
-func Decrypt(iv, data []byte) (output []byte, err error) {
-	output, err = aes.Decrypt(sharedKey, iv, data)
+func (s *Session) Decrypt(iv, data []byte) (output []byte, err error) {
+	switch s.cipher {
+	case CipherAESGCM:
+		output, err = aes.DecryptGCM(s.sharedKey, iv, data)
+	case CipherChaCha20Poly1305:
+		output, err = chacha20.Decrypt(s.sharedKey, iv, data)
+	default:
+		output, err = aes.Decrypt(s.sharedKey, iv, data)
+	}
 	return
 }
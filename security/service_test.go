@@ -0,0 +1,129 @@
+package security
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/gecosys/gsc-go/message"
+	x25519 "github.com/gecosys/gsc-go/security/x25519"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// fixedReader returns the same byte forever, so keys are deterministic
+type fixedReader byte
+
+func (r fixedReader) Read(p []byte) (int, error) {
+	for idx := range p {
+		p[idx] = byte(r)
+	}
+	return len(p), nil
+}
+
+func ephemeralKey(t *testing.T, key []byte) []byte {
+	data, err := proto.Marshal(&pb.EphemeralKey{ID: "exchange", Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestNewSessionX25519(t *testing.T) {
+	hubPrivate, hubPublic, err := x25519.GenerateKey(fixedReader(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cipher := range []CipherSuite{CipherAESCBC, CipherAESGCM, CipherChaCha20Poly1305} {
+		session, err := NewSessionX25519(ephemeralKey(t, hubPublic), SessionOptions{
+			Cipher: cipher,
+			Rand:   fixedReader(3),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.GetExchangeID() != "exchange" {
+			t.Fatalf("GetExchangeID() = %q", session.GetExchangeID())
+		}
+
+		// GSCHub derives the key from the public key sent by the client
+		clientPublic, err := session.GetSharedKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		salt := append(append([]byte{}, clientPublic...), hubPublic...)
+		hubKey, err := x25519.DeriveKey(hubPrivate, clientPublic, salt, infoX25519, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(hubKey, session.sharedKey) == false {
+			t.Fatalf("cipher %d: keys of client and hub are different", cipher)
+		}
+
+		// The same random gives the same session
+		again, err := NewSessionX25519(ephemeralKey(t, hubPublic), SessionOptions{
+			Cipher: cipher,
+			Rand:   fixedReader(3),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(again.sharedKey, session.sharedKey) == false {
+			t.Fatalf("cipher %d: session is not deterministic", cipher)
+		}
+
+		iv, data, err := session.Encrypt([]byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := again.Decrypt(iv, data)
+		if err != nil || string(output) != "hello" {
+			t.Fatalf("cipher %d: Decrypt() = %q, %v", cipher, output, err)
+		}
+	}
+}
+
+func TestNewSessionX25519RejectsBadKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"low order", ephemeralKey(t, make([]byte, x25519.KeySize))},
+		{"short", ephemeralKey(t, make([]byte, x25519.KeySize-1))},
+		{"long", ephemeralKey(t, make([]byte, x25519.KeySize+1))},
+		{"missing id", func() []byte {
+			data, _ := proto.Marshal(&pb.EphemeralKey{Key: make([]byte, x25519.KeySize)})
+			return data
+		}()},
+		{"malformed", []byte{0xff, 0xff}},
+	}
+	for _, test := range tests {
+		_, err := NewSessionX25519(test.key, SessionOptions{Rand: fixedReader(3)})
+		if err == nil {
+			t.Errorf("%s: NewSessionX25519() succeeded", test.name)
+		}
+	}
+}
+
+func TestSessionDecryptTampered(t *testing.T) {
+	_, hubPublic, _ := x25519.GenerateKey(fixedReader(7))
+	for _, cipher := range []CipherSuite{CipherAESGCM, CipherChaCha20Poly1305} {
+		session, err := NewSessionX25519(ephemeralKey(t, hubPublic), SessionOptions{Cipher: cipher})
+		if err != nil {
+			t.Fatal(err)
+		}
+		iv, data, err := session.Encrypt([]byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[0] ^= 1
+		_, err = session.Decrypt(iv, data)
+		if err != ErrAuthentication {
+			t.Errorf("cipher %d: Decrypt() error = %v, want ErrAuthentication", cipher, err)
+		}
+		_, err = session.Decrypt(iv, data[:3])
+		if err != ErrInvalidCipher {
+			t.Errorf("cipher %d: Decrypt() error = %v, want ErrInvalidCipher", cipher, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package security
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	stdrsa "crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+
+	pb "github.com/gecosys/gsc-go/message"
+	rsa "github.com/gecosys/gsc-go/security/rsa"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// UntrustedKeyError is returned when the key served by GSCHub
+// doesn't match the pinned key or its signature is wrong
+type UntrustedKeyError struct {
+	Reason string
+}
+
+func (e *UntrustedKeyError) Error() string {
+	return "Untrusted key of GSCHub: " + e.Reason
+}
+
+// Trust verifies keys served by GSCHub
+type Trust struct {
+	// fingerprint is SHA-256 of the public key RSA in DER (PKIX) format
+	fingerprint []byte
+	publicKey   *stdrsa.PublicKey
+	signingKey  ed25519.PublicKey
+}
+
+// NewTrust creates Trust from settings of config, empty settings are not checked.
+// It returns nil if all settings are empty.
+// Input:
+//  fingerprint: SHA-256 of public key RSA in DER (PKIX) format, in hex (colons are allowed) or base64
+//  pemKey: public key RSA in PEM format
+//  signingKey: Ed25519 public key in base64, used to verify signatures of key responses
+// Output:
+//  trust: the trust
+//  err: error occurred
+func NewTrust(fingerprint, pemKey, signingKey string) (trust *Trust, err error) {
+	if fingerprint == "" && pemKey == "" && signingKey == "" {
+		return nil, nil
+	}
+
+	trust = new(Trust)
+	if fingerprint != "" {
+		trust.fingerprint, err = parseFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pemKey != "" {
+		block, _ := pem.Decode([]byte(pemKey))
+		if block == nil {
+			return nil, errors.New("Invalid PEM of hub key")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := key.(*stdrsa.PublicKey)
+		if ok == false {
+			return nil, errors.New("Hub key is not RSA")
+		}
+		trust.publicKey = publicKey
+	}
+
+	if signingKey != "" {
+		key, err := base64.StdEncoding.DecodeString(signingKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("Invalid hub signing key")
+		}
+		trust.signingKey = ed25519.PublicKey(key)
+	}
+	return trust, nil
+}
+
+func parseFingerprint(fingerprint string) ([]byte, error) {
+	fingerprint = strings.TrimPrefix(fingerprint, "sha256/")
+	output, err := hex.DecodeString(strings.Replace(fingerprint, ":", "", -1))
+	if err != nil {
+		output, err = base64.StdEncoding.DecodeString(fingerprint)
+	}
+	if err != nil || len(output) != sha256.Size {
+		return nil, errors.New("Invalid fingerprint of hub key")
+	}
+	return output, nil
+}
+
+// VerifySignature checks Ed25519 signature of data of a key response.
+// It is skipped if no signing key is pinned.
+func (t *Trust) VerifySignature(data, signature []byte) error {
+	if t.signingKey == nil {
+		return nil
+	}
+	if len(signature) == 0 {
+		return &UntrustedKeyError{Reason: "response is not signed"}
+	}
+	if ed25519.Verify(t.signingKey, data, signature) == false {
+		return &UntrustedKeyError{Reason: "wrong signature"}
+	}
+	return nil
+}
+
+// VerifyPublicKey checks public key RSA (pb.PublicKey) against the pinned fingerprint and key
+func (t *Trust) VerifyPublicKey(key []byte) error {
+	if t.fingerprint == nil && t.publicKey == nil {
+		return nil
+	}
+
+	eKey := pb.PublicKey{}
+	err := proto.Unmarshal(key, &eKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := rsa.ParsePublicKey(eKey.E, eKey.N)
+	if err != nil {
+		return err
+	}
+	stdKey, err := publicKey.Std()
+	if err != nil {
+		return err
+	}
+
+	if t.publicKey != nil {
+		if t.publicKey.E != stdKey.E || t.publicKey.N.Cmp(stdKey.N) != 0 {
+			return &UntrustedKeyError{Reason: "key doesn't match the pinned key"}
+		}
+	}
+
+	if t.fingerprint != nil {
+		der, err := x509.MarshalPKIXPublicKey(stdKey)
+		if err != nil {
+			return err
+		}
+		fingerprint := sha256.Sum256(der)
+		if bytes.Equal(fingerprint[:], t.fingerprint) == false {
+			return &UntrustedKeyError{Reason: "fingerprint doesn't match"}
+		}
+	}
+	return nil
+}
+
+// HasSigningKey reports whether responses of key are required to be signed
+func (t *Trust) HasSigningKey() bool {
+	return t.signingKey != nil
+}
+
+// HasPinnedKey reports whether the public key RSA is pinned,
+// pinned keys can't be checked in X25519 handshake because its keys are ephemeral
+func (t *Trust) HasPinnedKey() bool {
+	return t.fingerprint != nil || t.publicKey != nil
+}
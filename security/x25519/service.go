@@ -0,0 +1,43 @@
+package x25519
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is size of private and public keys
+const KeySize = curve25519.ScalarSize
+
+// GenerateKey generates an ephemeral key pair, private key is read from random
+func GenerateKey(random io.Reader) (private, public []byte, err error) {
+	private = make([]byte, KeySize)
+	_, err = io.ReadFull(random, private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	public, err = curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, public, nil
+}
+
+// DeriveKey computes the shared secret of private and peer's public key
+// and expands it to size bytes with HKDF-SHA256
+func DeriveKey(private, peerPublic, salt, info []byte, size int) ([]byte, error) {
+	secret, err := curve25519.X25519(private, peerPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, size)
+	_, err = io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
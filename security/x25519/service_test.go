@@ -0,0 +1,68 @@
+package x25519
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fixedReader returns the same byte forever, so keys are deterministic
+type fixedReader byte
+
+func (r fixedReader) Read(p []byte) (int, error) {
+	for idx := range p {
+		p[idx] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestDeriveKeyMatches(t *testing.T) {
+	privateA, publicA, err := GenerateKey(fixedReader(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateB, publicB, err := GenerateKey(fixedReader(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	salt := append(append([]byte{}, publicA...), publicB...)
+	keyA, err := DeriveKey(privateA, publicB, salt, []byte("info"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := DeriveKey(privateB, publicA, salt, []byte("info"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(keyA, keyB) == false {
+		t.Fatal("keys derived by both sides are different")
+	}
+
+	// The same random gives the same key pair
+	_, again, _ := GenerateKey(fixedReader(1))
+	if bytes.Equal(again, publicA) == false {
+		t.Fatal("GenerateKey() is not deterministic")
+	}
+}
+
+func TestDeriveKeyRejectsBadPeerKeys(t *testing.T) {
+	private, _, err := GenerateKey(fixedReader(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"low order", make([]byte, KeySize)},
+		{"short", make([]byte, KeySize-1)},
+		{"long", make([]byte, KeySize+1)},
+	}
+	for _, test := range tests {
+		_, err := DeriveKey(private, test.key, nil, nil, 32)
+		if err == nil {
+			t.Errorf("%s: DeriveKey() succeeded", test.name)
+		}
+	}
+}
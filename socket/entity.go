@@ -17,4 +17,6 @@ type GEResponse struct {
 	ReturnCode int
 	Data       string
 	Timestamp  int64
+	// Signature is Ed25519 signature of decoded Data in base64, set by hubs signing their keys
+	Signature string
 }
@@ -0,0 +1,39 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxFrameSize is the maximum size of received frames if Options.MaxFrameSize is not set
+const DefaultMaxFrameSize = 16 << 20
+
+// ErrFrameTooLarge is matched by FrameTooLargeError with errors.Is
+var ErrFrameTooLarge = errors.New("Frame is too large")
+
+// FrameTooLargeError is returned when the header of a received frame
+// announces more bytes than Options.MaxFrameSize
+type FrameTooLargeError struct {
+	Size uint32
+	Max  uint32
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("Frame is too large: %d bytes (max %d)", e.Size, e.Max)
+}
+
+// Is reports whether target is ErrFrameTooLarge
+func (e *FrameTooLargeError) Is(target error) bool {
+	return target == ErrFrameTooLarge
+}
+
+// FramePolicy decides what to do with frames larger than Options.MaxFrameSize
+type FramePolicy int
+
+const (
+	// FrameClose closes the socket, FrameTooLargeError is returned by Err
+	FrameClose FramePolicy = iota
+	// FrameDrop skips the body of the frame without reading it into memory,
+	// FrameTooLargeError is sent to the error channel of ListenMessage
+	FrameDrop
+)
@@ -0,0 +1,122 @@
+package socket
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/gecosys/gsc-go/message"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const testMaxFrameSize = 64
+
+// frame encodes body like the hub, a little endian size followed by body
+func frame(body []byte) []byte {
+	header := make([]byte, 4)
+	binary.LittleEndian.PutUint32(header, uint32(len(body)))
+	return append(header, body...)
+}
+
+// plainFrame is a frame of reply which is not encrypted, so no session is needed
+func plainFrame(t *testing.T, reply *pb.Reply) []byte {
+	data, err := proto.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := proto.Marshal(&pb.Cipher{Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame(body)
+}
+
+// listenPipe returns channels of a socket reading frames from the other end of a pipe,
+// the frames are written by another goroutine
+func listenPipe(t *testing.T, policy FramePolicy, frames ...[]byte) (GEHSocket, chan *pb.Reply, chan error) {
+	local, remote := net.Pipe()
+	s := NewSocket(local, nil, &Options{MaxFrameSize: testMaxFrameSize, FramePolicy: policy})
+	go func() {
+		defer remote.Close()
+		for _, data := range frames {
+			// The socket may be closed before all frames are written
+			_, err := remote.Write(data)
+			if err != nil {
+				return
+			}
+		}
+		// Keep the pipe open until the socket is closed
+		remote.Read(make([]byte, 1))
+	}()
+	chanMessage, chanError := s.ListenMessage()
+	return s, chanMessage, chanError
+}
+
+func TestFrameDrop(t *testing.T) {
+	s, chanMessage, chanError := listenPipe(t, FrameDrop,
+		frame(make([]byte, testMaxFrameSize+1)),
+		plainFrame(t, &pb.Reply{Sender: "hub", Data: []byte("next")}),
+	)
+	defer s.Close()
+
+	select {
+	case err := <-chanError:
+		if errors.Is(err, ErrFrameTooLarge) == false {
+			t.Fatalf("error = %v, want ErrFrameTooLarge", err)
+		}
+		var frameErr *FrameTooLargeError
+		if errors.As(err, &frameErr) == false || frameErr.Size != testMaxFrameSize+1 || frameErr.Max != testMaxFrameSize {
+			t.Fatalf("error = %#v, want size %d and max %d", err, testMaxFrameSize+1, testMaxFrameSize)
+		}
+	case msg := <-chanMessage:
+		t.Fatalf("message %v is received before the error of the large frame", msg)
+	case <-time.After(time.Second):
+		t.Fatal("no error for the large frame")
+	}
+
+	// The frame after the dropped one is still parsed
+	select {
+	case msg := <-chanMessage:
+		if msg.Sender != "hub" || string(msg.Data) != "next" {
+			t.Fatalf("message = %v, want the frame after the large one", msg)
+		}
+	case err := <-chanError:
+		t.Fatalf("error = %v, want the frame after the large one", err)
+	case <-time.After(time.Second):
+		t.Fatal("the frame after the large one is not received")
+	}
+}
+
+func TestFrameClose(t *testing.T) {
+	s, chanMessage, chanError := listenPipe(t, FrameClose,
+		frame(make([]byte, testMaxFrameSize+1)),
+		plainFrame(t, &pb.Reply{Sender: "hub", Data: []byte("next")}),
+	)
+	defer s.Close()
+
+	select {
+	case msg, ok := <-chanMessage:
+		if ok {
+			t.Fatalf("message %v is received after the large frame", msg)
+		}
+	case err := <-chanError:
+		t.Fatalf("error %v is sent to the channel, want the socket closed", err)
+	case <-time.After(time.Second):
+		t.Fatal("the socket is not closed")
+	}
+	_, ok := <-chanError
+	if ok {
+		t.Fatal("the error channel is not closed")
+	}
+
+	frameErr, ok := s.Err().(*FrameTooLargeError)
+	if ok == false {
+		t.Fatalf("Err() = %v, want *FrameTooLargeError", s.Err())
+	}
+	if frameErr.Size != testMaxFrameSize+1 || frameErr.Max != testMaxFrameSize {
+		t.Fatalf("Err() = %#v, want size %d and max %d", frameErr, testMaxFrameSize+1, testMaxFrameSize)
+	}
+}
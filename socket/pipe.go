@@ -0,0 +1,93 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrPipeClosed is returned by Accept of a closed pipe listener
+var ErrPipeClosed = errors.New("Pipe listener is closed")
+
+var (
+	mtxPipes sync.Mutex
+	pipes    = make(map[string]*pipeListener)
+)
+
+// ListenPipe listens in-memory connections dialed with address "pipe://name",
+// it is used to run GSCHub in the same process (e.g. tests)
+func ListenPipe(name string) (net.Listener, error) {
+	mtxPipes.Lock()
+	defer mtxPipes.Unlock()
+	if _, ok := pipes[name]; ok {
+		return nil, errors.New("Pipe is already listened: " + name)
+	}
+
+	listener := &pipeListener{
+		name:      name,
+		chanConn:  make(chan net.Conn),
+		chanClose: make(chan struct{}),
+	}
+	pipes[name] = listener
+	return listener, nil
+}
+
+func dialPipe(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	mtxPipes.Lock()
+	listener, ok := pipes[address]
+	mtxPipes.Unlock()
+	if ok == false {
+		return nil, errors.New("Pipe is not listened: " + address)
+	}
+
+	client, server := net.Pipe()
+	select {
+	case listener.chanConn <- server:
+		return client, nil
+	case <-listener.chanClose:
+		return nil, ErrPipeClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type pipeListener struct {
+	name      string
+	chanConn  chan net.Conn
+	onceClose sync.Once
+	chanClose chan struct{}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.chanConn:
+		return conn, nil
+	case <-l.chanClose:
+		return nil, ErrPipeClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.onceClose.Do(func() {
+		close(l.chanClose)
+		mtxPipes.Lock()
+		delete(pipes, l.name)
+		mtxPipes.Unlock()
+	})
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.name)
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string {
+	return SchemePipe
+}
+
+func (a pipeAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,140 @@
+package socket
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyError is returned when the proxy refuses to connect to GSCHub
+type ProxyError struct {
+	Proxy  string
+	Status string
+}
+
+func (e *ProxyError) Error() string {
+	return "Proxy " + e.Proxy + " refused to connect: " + e.Status
+}
+
+// dialStream connects to address ("host:port") directly or through the proxy of opts.
+// scheme ("http" or "https") is used to select the proxy, like requests of HTTP.
+func dialStream(ctx context.Context, scheme, address string, opts *Options) (net.Conn, error) {
+	var dialer net.Dialer
+	if opts.Proxy == nil {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	proxyURL, err := opts.Proxy(&url.URL{Scheme: scheme, Host: address})
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		return dialConnect(ctx, proxyURL, address)
+	case "socks5", "socks5h":
+		return dialSOCKS(ctx, proxyURL, address)
+	}
+	return nil, errors.New("Unsupported scheme of proxy: " + proxyURL.Scheme)
+}
+
+// dialConnect opens a tunnel to address by HTTP CONNECT
+func dialConnect(ctx context.Context, proxyURL *url.URL, address string) (net.Conn, error) {
+	var (
+		dialer    net.Dialer
+		proxyAddr = canonicalAddr(proxyURL)
+	)
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		conn, err = handshakeTLS(ctx, conn, proxyAddr, new(tls.Config))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth := proxyURL.User.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	var reader *bufio.Reader
+	err = handshakeContext(ctx, conn, func() error {
+		err := req.Write(conn)
+		if err != nil {
+			return err
+		}
+		reader = bufio.NewReader(conn)
+		res, err := http.ReadResponse(reader, req)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return &ProxyError{Proxy: proxyURL.Host, Status: res.Status}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reader.Buffered() > 0 {
+		// Bytes of GSCHub were read together with the response of the proxy
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// dialSOCKS connects to address through SOCKS5 proxy
+func dialSOCKS(ctx context.Context, proxyURL *url.URL, address string) (net.Conn, error) {
+	dialer, err := proxy.FromURL(proxyURL, new(net.Dialer))
+	if err != nil {
+		return nil, err
+	}
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		return contextDialer.DialContext(ctx, "tcp", address)
+	}
+	return dialer.Dial("tcp", address)
+}
+
+// canonicalAddr returns host:port of proxyURL, the port is added if it is missing
+func canonicalAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// bufferedConn reads bytes buffered by reader before reading Conn
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
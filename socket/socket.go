@@ -3,9 +3,13 @@ package socket
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"io"
+	"io/ioutil"
 	"net"
+	"sync"
+	"time"
 
 	pb "github.com/gecosys/gsc-go/message"
 	security "github.com/gecosys/gsc-go/security"
@@ -18,39 +22,205 @@ type GEHSocket interface {
 	Close()
 	GetSecretKey() string
 	SetSecretKey(key string)
+	// RotateKeys replaces the session and the secret key, messages under
+	// the previous ones are still accepted until grace passes
+	RotateKeys(session *security.Session, secretKey string, grace time.Duration)
+	// GetPreviousSecretKey returns the secret key replaced by RotateKeys,
+	// it is empty after the grace period
+	GetPreviousSecretKey() string
+	// SetPendingSession lets messages be decrypted with session before it is installed by RotateKeys,
+	// so messages sent by GSCHub right after switching keys are not dropped. nil removes it.
+	SetPendingSession(session *security.Session)
 	SendMessage(data []byte) error
-	ListenMessage() chan *pb.Reply
+	SendMessageContext(ctx context.Context, data []byte) error
+	// ListenMessage returns channels of received messages and of frames which
+	// can't be parsed (e.g. tampered frames), both are closed when the socket is closed
+	ListenMessage() (chan *pb.Reply, chan error)
+	// Err returns the reason why the channels of ListenMessage are closed
+	Err() error
 }
 
-// NewSocketClient creates socket connecting to GSCHub
-func NewSocketClient(address string) (GEHSocket, error) {
-	conn, err := net.Dial("tcp", address)
-	client := &socket{
+// NewSocketClient creates socket connecting to GSCHub.
+// Messages received by the socket are decrypted with the keys of session.
+func NewSocketClient(address string, session *security.Session) (GEHSocket, error) {
+	return NewSocketClientContext(context.Background(), address, session)
+}
+
+// NewSocketClientContext is the same as NewSocketClient,
+// but dialing is aborted when ctx is done
+func NewSocketClientContext(ctx context.Context, address string, session *security.Session) (GEHSocket, error) {
+	return DialContext(ctx, address, session, nil)
+}
+
+// NewSocket creates socket sending and receiving frames through conn,
+// it is used by transports which are not registered as dialers.
+// Only limits of frames of opts are used, nil is default options.
+func NewSocket(conn net.Conn, session *security.Session, opts *Options) GEHSocket {
+	maxFrameSize := uint32(DefaultMaxFrameSize)
+	framePolicy := FrameClose
+	if opts != nil {
+		if opts.MaxFrameSize > 0 {
+			maxFrameSize = opts.MaxFrameSize
+		}
+		framePolicy = opts.FramePolicy
+	}
+
+	return &socket{
 		conn:            conn,
+		maxFrameSize:    maxFrameSize,
+		framePolicy:     framePolicy,
+		session:         session,
 		chanNextMessage: make(chan *pb.Reply),
+		chanError:       make(chan error),
+		chanClose:       make(chan struct{}),
 	}
-	return client, err
 }
 
 type socket struct {
+	mtxWrite        sync.Mutex
 	conn            net.Conn
+	maxFrameSize    uint32
+	framePolicy     FramePolicy
+	mtxKeys         sync.RWMutex
+	session         *security.Session
 	secretKey       string
+	prevSession     *security.Session
+	prevSecretKey   string
+	pendingSession  *security.Session
+	graceUntil      time.Time
 	chanNextMessage chan *pb.Reply
+	chanError       chan error
+	onceClose       sync.Once
+	chanClose       chan struct{}
+	mtxErr          sync.Mutex
+	err             error
 }
 
+// Close closes the connection, it is safe to be called many times
 func (s *socket) Close() {
-	s.conn.Close()
+	s.onceClose.Do(func() {
+		close(s.chanClose)
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	})
+}
+
+func (s *socket) Err() error {
+	s.mtxErr.Lock()
+	defer s.mtxErr.Unlock()
+	return s.err
+}
+
+func (s *socket) setErr(err error) {
+	s.mtxErr.Lock()
+	defer s.mtxErr.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
 }
 
 func (s *socket) GetSecretKey() string {
+	s.mtxKeys.RLock()
+	defer s.mtxKeys.RUnlock()
 	return s.secretKey
 }
 
 func (s *socket) SetSecretKey(key string) {
+	s.mtxKeys.Lock()
+	defer s.mtxKeys.Unlock()
 	s.secretKey = key
 }
 
+func (s *socket) RotateKeys(session *security.Session, secretKey string, grace time.Duration) {
+	s.mtxKeys.Lock()
+	defer s.mtxKeys.Unlock()
+	s.prevSession, s.prevSecretKey = s.session, s.secretKey
+	s.graceUntil = time.Now().Add(grace)
+	s.session, s.secretKey = session, secretKey
+	s.pendingSession = nil
+}
+
+func (s *socket) SetPendingSession(session *security.Session) {
+	s.mtxKeys.Lock()
+	defer s.mtxKeys.Unlock()
+	s.pendingSession = session
+}
+
+func (s *socket) GetPreviousSecretKey() string {
+	s.mtxKeys.RLock()
+	defer s.mtxKeys.RUnlock()
+	if time.Now().After(s.graceUntil) {
+		return ""
+	}
+	return s.prevSecretKey
+}
+
+// getSessions returns the current session, the pending one
+// and the previous one during the grace period
+func (s *socket) getSessions() (*security.Session, *security.Session, *security.Session) {
+	s.mtxKeys.RLock()
+	defer s.mtxKeys.RUnlock()
+	if time.Now().After(s.graceUntil) {
+		return s.session, s.pendingSession, nil
+	}
+	return s.session, s.pendingSession, s.prevSession
+}
+
 func (s *socket) SendMessage(data []byte) error {
+	return s.SendMessageContext(context.Background(), data)
+}
+
+// SendMessageContext writes a frame, the writing is interrupted when ctx is done.
+// The socket is closed if writing fails, because the frame may be written partially.
+func (s *socket) SendMessageContext(ctx context.Context, data []byte) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	s.mtxWrite.Lock()
+	defer s.mtxWrite.Unlock()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	if ctx.Done() != nil {
+		var (
+			chanStop = make(chan struct{})
+			chanDone = make(chan struct{})
+		)
+		go func() {
+			defer close(chanDone)
+			select {
+			case <-ctx.Done():
+				// Unblock the pending write
+				s.conn.SetWriteDeadline(time.Unix(1, 0))
+			case <-chanStop:
+			}
+		}()
+		defer func() {
+			close(chanStop)
+			<-chanDone
+			// The watcher may set the deadline after the write finished
+			s.conn.SetWriteDeadline(time.Time{})
+		}()
+	}
+
+	err = s.write(data)
+	if err != nil {
+		// The stream may contain a part of the frame, it can't be used anymore
+		s.setErr(err)
+		s.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (s *socket) write(data []byte) error {
 	header := make([]byte, 4)
 	binary.LittleEndian.PutUint32(header, uint32(len(data)))
 	buffer := bytes.NewBuffer(header)
@@ -68,7 +238,7 @@ func (s *socket) SendMessage(data []byte) error {
 	return w.Flush()
 }
 
-func (s *socket) ListenMessage() chan *pb.Reply {
+func (s *socket) ListenMessage() (chan *pb.Reply, chan error) {
 	go func() {
 		var (
 			err      error
@@ -85,27 +255,67 @@ func (s *socket) ListenMessage() chan *pb.Reply {
 			if bodySize == 0 { // Get size of body
 				nBytes, err = io.ReadAtLeast(reader, header, 4)
 				if nBytes != 4 || err != nil { // io.EOF || other errors
+					s.setErr(err)
 					s.Close()
 					break LOOP
 				}
 				bodySize = binary.LittleEndian.Uint32(header)
 			}
 
+			if bodySize > s.maxFrameSize {
+				err = &FrameTooLargeError{Size: bodySize, Max: s.maxFrameSize}
+				if s.framePolicy != FrameDrop {
+					s.setErr(err)
+					s.Close()
+					break LOOP
+				}
+
+				// Skip the body, so the next header can be read
+				_, dropErr := io.CopyN(ioutil.Discard, reader, int64(bodySize))
+				if dropErr != nil { // io.EOF || other errors
+					s.setErr(dropErr)
+					s.Close()
+					break LOOP
+				}
+				bodySize = 0
+				select {
+				case s.chanError <- err:
+				case <-s.chanClose:
+					s.setErr(io.ErrClosedPipe)
+					break LOOP
+				}
+				continue
+			}
+
 			data, err = s.getBody(reader, bodySize)
 			if err != nil { // io.EOF || other errors
+				s.setErr(err)
 				s.Close()
 				break LOOP
 			}
 			bodySize = 0
 
 			message, err = s.parseMessage(data)
-			if err == nil {
-				s.chanNextMessage <- message
+			if err != nil {
+				select {
+				case s.chanError <- err:
+				case <-s.chanClose:
+					s.setErr(io.ErrClosedPipe)
+					break LOOP
+				}
+				continue
+			}
+			select {
+			case s.chanNextMessage <- message:
+			case <-s.chanClose:
+				s.setErr(io.ErrClosedPipe)
+				break LOOP
 			}
 		}
 		close(s.chanNextMessage)
+		close(s.chanError)
 	}()
-	return s.chanNextMessage
+	return s.chanNextMessage, s.chanError
 }
 
 func (s *socket) getBody(reader *bufio.Reader, size uint32) ([]byte, error) {
@@ -128,13 +338,37 @@ func (s *socket) parseMessage(data []byte) (*pb.Reply, error) {
 		return nil, err
 	}
 
-	data = cipher.Data
-	if len(cipher.IV) > 0 {
-		data, err = security.Decrypt(cipher.IV, data)
-		if err != nil {
-			return nil, err
+	if len(cipher.IV) == 0 {
+		message := new(pb.Reply)
+		err = proto.Unmarshal(cipher.Data, message)
+		return message, err
+	}
+
+	session, pendingSession, prevSession := s.getSessions()
+	message, err := decryptMessage(session, &cipher)
+	if err != nil && pendingSession != nil {
+		// The message may be sent after GSCHub switched to the new keys,
+		// before they are installed
+		pendingMessage, pendingErr := decryptMessage(pendingSession, &cipher)
+		if pendingErr == nil {
+			return pendingMessage, nil
 		}
 	}
+	if err != nil && prevSession != nil {
+		// The message may be sent before the keys were rotated
+		prevMessage, prevErr := decryptMessage(prevSession, &cipher)
+		if prevErr == nil {
+			return prevMessage, nil
+		}
+	}
+	return message, err
+}
+
+func decryptMessage(session *security.Session, cipher *pb.Cipher) (*pb.Reply, error) {
+	data, err := session.Decrypt(cipher.IV, cipher.Data)
+	if err != nil {
+		return nil, err
+	}
 
 	message := new(pb.Reply)
 	err = proto.Unmarshal(data, message)
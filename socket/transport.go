@@ -0,0 +1,193 @@
+package socket
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	security "github.com/gecosys/gsc-go/security"
+)
+
+// Schemes of addresses of dialers registered by default
+const (
+	SchemeTCP  = "tcp"
+	SchemeTLS  = "tls"
+	SchemePipe = "pipe"
+	SchemeWS   = "ws"
+	SchemeWSS  = "wss"
+	SchemeUnix = "unix"
+)
+
+// ErrUnknownScheme is returned when no dialer is registered for the scheme of an address
+var ErrUnknownScheme = errors.New("Unknown scheme of address")
+
+// Options configures sockets created by DialContext
+type Options struct {
+	// TLSConfig is used by scheme tls, ServerName is host of the address if it is empty.
+	// Addresses without scheme use tls if it is set, otherwise tcp.
+	TLSConfig *tls.Config
+	// Proxy returns the proxy (http, https, socks5 or socks5h) to reach the URL
+	// of GSCHub with scheme http (tcp, ws) or https (tls, wss), nil means no proxy.
+	// Unix domain sockets and pipes are not proxied (default: no proxy).
+	Proxy func(*url.URL) (*url.URL, error)
+	// MaxFrameSize is the maximum size of received frames (default: DefaultMaxFrameSize)
+	MaxFrameSize uint32
+	// FramePolicy decides what to do with larger frames (default: FrameClose)
+	FramePolicy FramePolicy
+}
+
+// Dialer creates connections which carry frames of sockets.
+// address is passed without its scheme.
+type Dialer interface {
+	DialContext(ctx context.Context, address string, opts *Options) (net.Conn, error)
+}
+
+// DialerFunc is a function used as Dialer
+type DialerFunc func(ctx context.Context, address string, opts *Options) (net.Conn, error)
+
+// DialContext calls f
+func (f DialerFunc) DialContext(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	return f(ctx, address, opts)
+}
+
+var (
+	mtxDialers sync.RWMutex
+	dialers    = map[string]Dialer{
+		SchemeTCP:  DialerFunc(dialTCP),
+		SchemeTLS:  DialerFunc(dialTLS),
+		SchemePipe: DialerFunc(dialPipe),
+		SchemeWS:   DialerFunc(dialWebSocket),
+		SchemeWSS:  DialerFunc(dialWebSocketTLS),
+		SchemeUnix: DialerFunc(dialUnix),
+	}
+)
+
+// RegisterDialer sets dialer of addresses with scheme (e.g. "tcp" of "tcp://host:port"),
+// it replaces the previous dialer of the scheme
+func RegisterDialer(scheme string, dialer Dialer) {
+	mtxDialers.Lock()
+	defer mtxDialers.Unlock()
+	dialers[scheme] = dialer
+}
+
+func getDialer(scheme string) (Dialer, bool) {
+	mtxDialers.RLock()
+	defer mtxDialers.RUnlock()
+	dialer, ok := dialers[scheme]
+	return dialer, ok
+}
+
+// DialContext creates socket connecting to GSCHub at address (e.g. "tcp://host:port")
+// with the dialer of its scheme. opts is passed to the dialer, nil is default options.
+func DialContext(ctx context.Context, address string, session *security.Session, opts *Options) (GEHSocket, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	scheme, address := splitAddress(address, opts)
+	dialer, ok := getDialer(scheme)
+	if ok == false {
+		return nil, ErrUnknownScheme
+	}
+
+	conn, err := dialer.DialContext(ctx, address, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewSocket(conn, session, opts), nil
+}
+
+// splitAddress returns scheme of address and the rest of it
+func splitAddress(address string, opts *Options) (string, string) {
+	idx := strings.Index(address, "://")
+	if idx >= 0 {
+		return strings.ToLower(address[:idx]), address[idx+3:]
+	}
+	if opts.TLSConfig != nil {
+		return SchemeTLS, address
+	}
+	return SchemeTCP, address
+}
+
+func dialTCP(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	return dialStream(ctx, "http", address, opts)
+}
+
+// dialUnix connects to the Unix domain socket at path address
+func dialUnix(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", address)
+}
+
+func dialTLS(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	conn, err := dialStream(ctx, "https", address, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	config := opts.TLSConfig
+	if config == nil {
+		config = new(tls.Config)
+	}
+	return handshakeTLS(ctx, conn, address, config)
+}
+
+// handshakeTLS wraps conn with TLS, conn is closed if the handshake fails
+func handshakeTLS(ctx context.Context, conn net.Conn, address string, config *tls.Config) (net.Conn, error) {
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	err := handshakeContext(ctx, conn, tlsConn.Handshake)
+	if err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// handshakeContext runs handshake on conn, it is interrupted when ctx is done.
+// conn is closed if the handshake fails.
+func handshakeContext(ctx context.Context, conn net.Conn, handshake func() error) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var (
+		chanStop = make(chan struct{})
+		chanDone = make(chan struct{})
+	)
+	go func() {
+		defer close(chanDone)
+		select {
+		case <-ctx.Done():
+			// Unblock the pending handshake
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-chanStop:
+		}
+	}()
+
+	err := handshake()
+	close(chanStop)
+	<-chanDone
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package socket
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialWebSocket connects to address ("host:port/path") by WebSocket,
+// every frame of the socket is sent as a binary message
+func dialWebSocket(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	return dialWS(ctx, SchemeWS, address, opts)
+}
+
+// dialWebSocketTLS is the same as dialWebSocket, but over TLS
+func dialWebSocketTLS(ctx context.Context, address string, opts *Options) (net.Conn, error) {
+	return dialWS(ctx, SchemeWSS, address, opts)
+}
+
+func dialWS(ctx context.Context, scheme, address string, opts *Options) (net.Conn, error) {
+	origin := "http://localhost"
+	port := "80"
+	if scheme == SchemeWSS {
+		origin = "https://localhost"
+		port = "443"
+	}
+
+	config, err := websocket.NewConfig(scheme+"://"+address, origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Origin.Host = config.Location.Host
+
+	hostPort := config.Location.Host
+	if config.Location.Port() == "" {
+		hostPort = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	var conn net.Conn
+	if scheme == SchemeWSS {
+		conn, err = dialTLS(ctx, hostPort, opts)
+	} else {
+		conn, err = dialTCP(ctx, hostPort, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ws *websocket.Conn
+	err = handshakeContext(ctx, conn, func() error {
+		var err error
+		ws, err = websocket.NewClient(config, conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}